@@ -0,0 +1,379 @@
+//
+// FilePath    : video-trim\auth.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 可选的访问认证: 共享密码登录和 API 令牌, 密码和令牌只以 bcrypt 哈希保存, 登录失败按 IP 限制频率
+//
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authCookieName 登录 Cookie 名称
+const authCookieName = "vt_auth"
+
+// authEnabled 判断是否配置了访问密码或 API 令牌
+func authEnabled() bool {
+	return authPasswordHash != "" || len(authTokenHashes) > 0
+}
+
+// withAuth 启用认证时要求每个请求已登录或携带有效的 API 令牌, 登录页面本身除外
+// 需要放在 withSession 内层: 登录状态与会话绑定, 令牌请求使用由令牌确定的会话
+func withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 登录和扫码配对的地址不需要认证
+		if !authEnabled() || r.URL.Path == "/login" || r.URL.Path == "/logout" || strings.HasPrefix(r.URL.Path, "/pair/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// 已配对的设备无需登录, 撤销后需要重新登录或配对
+		if _, ok := deviceFromRequest(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token, ok := bearerToken(r); ok {
+			if wait := loginAttempts.retryAfter(clientIP(r)); wait > 0 {
+				respondTooManyAttempts(w, r, wait)
+				return
+			}
+
+			if !verifyAuthToken(token) {
+				loginAttempts.fail(clientIP(r))
+				log.Printf("auth: invalid token from %s", clientIP(r))
+				respondUnauthorized(w, r)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, tokenSession(token))))
+
+			return
+		}
+
+		if !validLoginCookie(r) {
+			respondUnauthorized(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// respondUnauthorized 浏览器打开页面时跳转到登录页面, 其他请求返回 401
+func respondUnauthorized(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && !wantsJSON(r) && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="video-trim"`)
+	http.Error(w, getLocale(detectLangFromRequest(r))[KeyAuthRequired], http.StatusUnauthorized)
+}
+
+// respondTooManyAttempts 登录失败次数过多时返回 429 及重试等待时间
+func respondTooManyAttempts(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	setRetryAfter(w, wait)
+	http.Error(w, loginLockedMessage(detectLangFromRequest(r), wait), http.StatusTooManyRequests)
+}
+
+// setRetryAfter 设置 Retry-After 响应头(秒, 向上取整)
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+// loginLockedMessage 返回登录被暂时拒绝的提示, 等待时间按分钟向上取整
+func loginLockedMessage(lang string, wait time.Duration) string {
+	return fmt.Sprintf(getLocale(lang)[KeyLoginLocked], int(math.Ceil(wait.Minutes())))
+}
+
+// bearerToken 读取 Authorization: Bearer <令牌> 请求头
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
+// verifiedTokens 已验证通过的令牌(SHA-256), bcrypt 比较较慢, 避免每个请求都重新计算
+var verifiedTokens = struct {
+	sync.Mutex
+	m map[[32]byte]bool
+}{m: map[[32]byte]bool{}}
+
+// verifyAuthToken 判断令牌是否与配置的某个哈希匹配
+func verifyAuthToken(token string) bool {
+	sum := sha256.Sum256([]byte(token))
+
+	verifiedTokens.Lock()
+	ok := verifiedTokens.m[sum]
+	verifiedTokens.Unlock()
+
+	if ok {
+		return true
+	}
+
+	for _, h := range authTokenHashes {
+		if bcrypt.CompareHashAndPassword([]byte(h), []byte(token)) == nil {
+			verifiedTokens.Lock()
+			verifiedTokens.m[sum] = true
+			verifiedTokens.Unlock()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// tokenSession 返回令牌对应的会话 ID, 同一令牌的请求不需要 Cookie 也属于同一会话
+func tokenSession(token string) string {
+	sum := sha256.Sum256([]byte("token:" + token))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// signLogin 返回登录 Cookie 的签名, 签名包含会话 ID、过期时间和当前的密码哈希, 修改密码后旧的登录失效
+func signLogin(session string, expires int64) string {
+	mac := hmac.New(sha256.New, sessionKey)
+	fmt.Fprintf(mac, "login|%s|%d|%s", session, expires, authPasswordHash)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validLoginCookie 判断请求是否携带本会话有效且未过期的登录 Cookie
+func validLoginCookie(r *http.Request) bool {
+	if authPasswordHash == "" {
+		return false
+	}
+
+	c, err := r.Cookie(authCookieName)
+	if err != nil {
+		return false
+	}
+
+	exp, sig, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(signLogin(requestSession(r), expires)))
+}
+
+// attemptRecord 一个 IP 在统计窗口内的登录失败记录
+type attemptRecord struct {
+	count int       // 失败次数
+	first time.Time // 窗口内第一次失败的时间
+}
+
+// attemptLimiter 按 IP 统计登录失败次数, 达到上限后在窗口结束前拒绝登录
+type attemptLimiter struct {
+	mu      sync.Mutex
+	records map[string]*attemptRecord
+}
+
+// loginAttempts 全局登录失败统计
+var loginAttempts = &attemptLimiter{records: map[string]*attemptRecord{}}
+
+// retryAfter 返回 ip 还需等待多久才能再次尝试, 未被锁定时返回 0
+func (l *attemptLimiter) retryAfter(ip string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec, ok := l.records[ip]
+	if !ok || rec.count < authMaxAttempts {
+		return 0
+	}
+
+	return max(time.Until(rec.first.Add(time.Duration(authLockoutMinutes)*time.Minute)), 0)
+}
+
+// fail 记录一次失败, 同时清理已过期的记录
+func (l *attemptLimiter) fail(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window := time.Duration(authLockoutMinutes) * time.Minute
+	now := time.Now()
+
+	for k, rec := range l.records {
+		if now.Sub(rec.first) > window {
+			delete(l.records, k)
+		}
+	}
+
+	rec, ok := l.records[ip]
+	if !ok {
+		rec = &attemptRecord{first: now}
+		l.records[ip] = rec
+	}
+
+	rec.count++
+
+	if rec.count == authMaxAttempts {
+		log.Printf("auth: too many failed attempts from %s, locked for %d minutes", ip, authLockoutMinutes)
+	}
+}
+
+// reset 登录成功后清除失败记录
+func (l *attemptLimiter) reset(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.records, ip)
+}
+
+// clientIP 返回客户端 IP(不信任代理头, 避免伪造绕过频率限制)
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// safeRedirect 只允许跳转到本站路径, 防止登录后被重定向到其他网站
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, `/\`) {
+		return "/"
+	}
+
+	return next
+}
+
+// handleLoginPage 渲染登录页面
+func handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	renderLogin(w, r, http.StatusOK, "")
+}
+
+// renderLogin 以指定状态码渲染登录页面, msg 为错误提示
+func renderLogin(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	lang := detectLangFromRequest(r)
+
+	data := struct {
+		Lang     string
+		I18n     map[string]string
+		Next     string
+		Error    string
+		Password bool
+	}{
+		Lang:     lang,
+		I18n:     getLocale(lang),
+		Next:     safeRedirect(r.FormValue("next")),
+		Error:    msg,
+		Password: authPasswordHash != "",
+	}
+
+	renderTemplate(w, status, "login", data)
+}
+
+// handleLogin 校验共享密码, 成功后下发与会话绑定的登录 Cookie 并跳转回原页面
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	i18n := getLocale(detectLangFromRequest(r))
+	ip := clientIP(r)
+
+	if authPasswordHash == "" {
+		renderLogin(w, r, http.StatusForbidden, i18n[KeyLoginDisabled])
+		return
+	}
+
+	if wait := loginAttempts.retryAfter(ip); wait > 0 {
+		setRetryAfter(w, wait)
+		renderLogin(w, r, http.StatusTooManyRequests, loginLockedMessage(detectLangFromRequest(r), wait))
+
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(authPasswordHash), []byte(r.FormValue("password"))) != nil {
+		loginAttempts.fail(ip)
+		log.Printf("auth: failed login from %s", ip)
+		renderLogin(w, r, http.StatusUnauthorized, i18n[KeyLoginFailed])
+
+		return
+	}
+
+	loginAttempts.reset(ip)
+
+	expires := time.Now().Add(time.Duration(authLoginDays) * 24 * time.Hour)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookieName,
+		Value:    strconv.FormatInt(expires.Unix(), 10) + "." + signLogin(requestSession(r), expires.Unix()),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, safeRedirect(r.FormValue("next")), http.StatusSeeOther)
+}
+
+// handleLogout 清除登录 Cookie 并返回登录页面
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// runHashPassword 从标准输入读取一行密码或令牌, 输出可写入 config.yaml 的 bcrypt 哈希
+func runHashPassword() {
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("read password error: %v", err)
+	}
+
+	secret := strings.TrimRight(line, "\r\n")
+	if secret == "" {
+		log.Fatal("password is empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		log.Fatalf("hash password error: %v", err)
+	}
+
+	fmt.Println(string(hash))
+}
@@ -0,0 +1,34 @@
+//
+// FilePath    : video-trim\auth_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 登录跳转地址校验的测试
+//
+
+package main
+
+import "testing"
+
+func TestSafeRedirect(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "/", want: "/"},
+		{in: "/library?page=2", want: "/library?page=2"},
+		{in: "/jobs/0123456789abcdef", want: "/jobs/0123456789abcdef"},
+		{in: "", want: "/"},
+		{in: "library", want: "/"},
+		{in: "//evil.example", want: "/"},
+		{in: `/\evil.example`, want: "/"},
+		{in: "https://evil.example/", want: "/"},
+		{in: "javascript:alert(1)", want: "/"},
+	}
+
+	for _, tt := range tests {
+		if got := safeRedirect(tt.in); got != tt.want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -10,35 +10,124 @@ package main
 
 import (
 	"log"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // 配置键
 const (
-	keyUploadDir           = "upload_dir"            // 上传目录
-	keyOutputDir           = "output_dir"            // 输出目录
-	keyHeadTrimSeconds     = "head_trim_seconds"     // 掐头秒数
-	keyTailSeconds         = "tail_seconds"          // 去尾秒数
-	keyServerPort          = "server_port"           // 服务器端口
-	keyMaxUploadSize       = "max_upload_size"       // 单个最大上传大小
-	keyReadTimeoutSeconds  = "read_timeout_seconds"  // 读取超时秒数
-	keyWriteTimeoutSeconds = "write_timeout_seconds" // 写入超时秒数
-	keyIdleTimeoutSeconds  = "idle_timeout_seconds"  // 空闲连接超时秒数
+	keyUploadDir           = "upload_dir"                 // 上传目录
+	keyOutputDir           = "output_dir"                 // 输出目录
+	keyHeadTrimSeconds     = "head_trim_seconds"          // 掐头秒数
+	keyTailSeconds         = "tail_seconds"               // 去尾秒数
+	keyServerPort          = "server_port"                // 服务器端口
+	keyMaxUploadSize       = "max_upload_size"            // 单个最大上传大小
+	keyReadTimeoutSeconds  = "read_timeout_seconds"       // 读取超时秒数
+	keyWriteTimeoutSeconds = "write_timeout_seconds"      // 写入超时秒数
+	keyIdleTimeoutSeconds  = "idle_timeout_seconds"       // 空闲连接超时秒数
+	keyWorkerCount         = "worker_count"               // 并行处理任务的 worker 数量
+	keyQueueSize           = "queue_size"                 // 任务队列容量
+	keyMaxProcessSeconds   = "max_process_seconds"        // 单个文件最长处理时间(秒)
+	keyJobTTLMinutes       = "job_ttl_minutes"            // 已结束的任务保留多久(分钟)
+	keyIntroDetectSeconds  = "intro_detect_seconds"       // 片头检测时抽取的开头秒数
+	keyHashSampleFPS       = "hash_sample_fps"            // 计算帧哈希时每秒抽取的帧数
+	keyHashMatchThreshold  = "hash_match_threshold"       // 帧哈希判定为相同画面的最大差异位数
+	keyReferenceDir        = "reference_dir"              // 参考片段存放目录
+	keyStateDir            = "state_dir"                  // 会话密钥、文件归属等状态文件存放目录
+	keyReferenceSearch     = "reference_search_seconds"   // 在开头/结尾查找参考片段的秒数
+	keyKeyframeSnap        = "keyframe_snap"              // 片段起点对齐关键帧的默认方式
+	keyStreamTypes         = "stream_types"               // 默认保留的流类型
+	keyCoverSearch         = "cover_search_seconds"       // 自动选择封面时搜索的开头秒数
+	keyThumbnailInterval   = "thumbnail_interval_seconds" // 时间轴缩略图间隔秒数
+	keyThumbnailMaxCount   = "thumbnail_max_count"        // 时间轴缩略图最大数量
+	keyStagedTTLMinutes    = "staged_ttl_minutes"         // 暂存文件未提交时保留多久(分钟)
+	keyPrivacyScrub        = "privacy_scrub"              // 默认是否去掉位置、设备等隐私元数据
+	keyRetentionMaxAge     = "retention_max_age_hours"    // 输出文件最长保留时间(小时)
+	keyRetentionMaxSize    = "retention_max_output_size"  // 输出目录最大总大小(字节)
+	keyDeleteAfterDownload = "delete_after_download"      // 首次完整下载后是否删除输出文件
+	keyRetentionInterval   = "retention_check_minutes"    // 自动清理的检查间隔(分钟)
+	keyMinFreeSpace        = "min_free_space"             // 接收上传后磁盘至少保留的可用空间(字节)
+	keySessionSecret       = "session_secret"             // 会话 Cookie 的签名密钥
+	keyAdminToken          = "admin_token"                // 管理员令牌, 可查看和清理全部文件
+	keyAuthPasswordHash    = "auth_password_hash"         // 访问密码的 bcrypt 哈希
+	keyAuthTokenHashes     = "auth_token_hashes"          // API 令牌的 bcrypt 哈希列表
+	keyAuthMaxAttempts     = "auth_max_attempts"          // 锁定前允许的登录失败次数
+	keyAuthLockoutMinutes  = "auth_lockout_minutes"       // 登录失败的统计和锁定时长(分钟)
+	keyAuthLoginDays       = "auth_login_days"            // 登录的有效天数
+	keyPairingTokenMinutes = "pairing_token_minutes"      // 配对二维码的有效时间(分钟)
+	keyBindInterface       = "bind_interface"             // 只在该网卡的地址上监听
+	keyAdvertiseHost       = "advertise_host"             // 控制台提示和配对二维码使用的主机名或 IP
+	keyMDNSEnabled         = "mdns_enabled"               // 是否通过 mDNS/DNS-SD 广播服务
+	keyMDNSHostname        = "mdns_hostname"              // mDNS 广播的主机名(不含 .local)
 )
 
 // 可配置变量(会被 config.yaml 覆盖)
 var (
-	uploadDir       = "./uploads" // 上传文件存放目录
-	outputDir       = "./outputs" // 输出文件存放目录
-	headTrimSeconds = 6           // 掐头:多少秒
-	tailSeconds     = 0           // 去尾:多少秒
-	serverPort      = ":7778"     // 服务器监听端口
+	uploadDir    = "./uploads"      // 上传文件存放目录
+	referenceDir = "./references"   // 参考片段(已知片头/片尾)存放目录
+	outputDir    = "./outputs"      // 输出文件存放目录
+	stateDir     = "./data"         // 会话密钥、文件归属和已配对设备等状态文件存放目录, 不对外提供下载
+	headTrim     = 6 * time.Second  // 掐头:时长, 配置支持小数秒和 HH:MM:SS.mmm
+	tailTrim     = time.Duration(0) // 去尾:时长, 格式同上
+	serverPort   = ":7778"          // 服务器监听端口
 	// 超时配置(秒)
 	readTimeoutSeconds        = 15         // 读取超时(秒)
 	writeTimeoutSeconds       = 60         // 写入超时(秒)
 	idleTimeoutSeconds        = 120        // 空闲连接超时(秒)
 	maxUploadSize       int64 = 2048 << 20 // 2048 MB, 单个文件最大允许上传大小(字节)
+	// 任务队列配置
+	workerCount = 2  // 并行处理任务的 worker 数量
+	queueSize   = 16 // 任务队列容量, 队列满时拒绝新的上传
+	// 单个文件最长处理时间(秒), 超时后结束 ffmpeg, 0 表示不限制
+	maxProcessSeconds = 3600
+	// 已结束的任务保留多久(分钟), 之后从内存中移除, 再查询返回 404
+	jobTTLMinutes = 60
+	// 帧哈希配置
+	introDetectSeconds = 60 // 片头检测时抽取的开头秒数
+	hashSampleFPS      = 2  // 计算帧哈希时每秒抽取的帧数
+	hashMatchThreshold = 10 // 帧哈希(64 位)差异不超过该位数时视为相同画面
+	// 在视频开头/结尾多少秒内查找参考片段, 同时也是参考片段的最大时长
+	referenceSearchSeconds = 120
+	// 自动选择封面时在输出视频开头多少秒内搜索
+	coverSearchSeconds = 120
+	// 时间轴缩略图配置
+	thumbnailIntervalSeconds = 5   // 相邻缩略图的间隔秒数
+	thumbnailMaxCount        = 100 // 缩略图最大数量, 超过时自动加大间隔
+	stagedTTLMinutes         = 120 // 暂存文件未提交时保留多久(分钟), 之后删除文件和缩略图
+	// 片段起点对齐关键帧的默认方式: back、forward、nearest 或 precise(重新编码边界部分)
+	keyframeSnap = snapBack
+	// 默认保留的流类型, 默认保留全部流
+	streamTypes = allStreamTypes
+	// 默认是否去掉位置、设备、编码器和用户备注等元数据
+	privacyScrub = false
+	// 自动清理配置, 0 表示不限制
+	retentionMaxAgeHours         = 0     // 输出文件及未完成的断点续传上传最长保留时间(小时)
+	retentionMaxOutputSize int64 = 0     // 输出目录最大总大小(字节), 超出时从最早的文件开始删除
+	deleteAfterDownload          = false // 首次完整下载后删除输出文件
+	retentionCheckMinutes        = 10    // 自动清理的检查间隔(分钟)
+	// 接收上传后磁盘至少保留的可用空间(字节), 上传需要约 2 倍文件大小的空间(上传文件和输出文件)
+	minFreeSpace int64 = 256 << 20
+	// 会话 Cookie 的签名密钥, 为空时自动生成并保存在状态目录中
+	sessionSecret = ""
+	// 管理员令牌, 为空时不启用管理员操作, 没有归属的输出文件所有人都可以访问
+	adminToken = ""
+	// 访问认证配置, 密码和令牌都未配置时不启用认证
+	authPasswordHash   = ""         // 访问密码的 bcrypt 哈希
+	authTokenHashes    = []string{} // API 令牌的 bcrypt 哈希
+	authMaxAttempts    = 5          // 同一 IP 锁定前允许的登录失败次数
+	authLockoutMinutes = 15         // 登录失败的统计和锁定时长(分钟)
+	authLoginDays      = 30         // 登录的有效天数
+	// 手机扫码配对的二维码有效时间(分钟), 每个二维码只能使用一次
+	pairingTokenMinutes = 30
+	// 网络配置
+	bindInterface = ""           // 只在该网卡的地址(IPv4 和 IPv6)上监听, 为空时监听全部地址
+	advertiseHost = ""           // 控制台提示和配对二维码使用的主机名或 IP, 为空时自动选择
+	advertisePort = ""           // advertise_host 中带有的端口, 为空时使用监听端口
+	mdnsEnabled   = false        // 是否通过 mDNS/DNS-SD 广播服务, 开启后手机可通过 <mdns_hostname>.local 访问
+	mdnsHostname  = "video-trim" // mDNS 广播的主机名
 )
 
 // 读取配置文件(如果存在)
@@ -52,13 +141,47 @@ func readConfig() {
 	// defaults
 	viper.SetDefault(keyUploadDir, uploadDir)
 	viper.SetDefault(keyOutputDir, outputDir)
-	viper.SetDefault(keyHeadTrimSeconds, headTrimSeconds)
-	viper.SetDefault(keyTailSeconds, tailSeconds)
+	viper.SetDefault(keyHeadTrimSeconds, formatSeconds(headTrim))
+	viper.SetDefault(keyTailSeconds, formatSeconds(tailTrim))
 	viper.SetDefault(keyServerPort, serverPort)
 	viper.SetDefault(keyMaxUploadSize, maxUploadSize)
 	viper.SetDefault(keyReadTimeoutSeconds, readTimeoutSeconds)
 	viper.SetDefault(keyWriteTimeoutSeconds, writeTimeoutSeconds)
 	viper.SetDefault(keyIdleTimeoutSeconds, idleTimeoutSeconds)
+	viper.SetDefault(keyWorkerCount, workerCount)
+	viper.SetDefault(keyQueueSize, queueSize)
+	viper.SetDefault(keyJobTTLMinutes, jobTTLMinutes)
+	viper.SetDefault(keyMaxProcessSeconds, maxProcessSeconds)
+	viper.SetDefault(keyIntroDetectSeconds, introDetectSeconds)
+	viper.SetDefault(keyHashSampleFPS, hashSampleFPS)
+	viper.SetDefault(keyHashMatchThreshold, hashMatchThreshold)
+	viper.SetDefault(keyReferenceDir, referenceDir)
+	viper.SetDefault(keyStateDir, stateDir)
+	viper.SetDefault(keyReferenceSearch, referenceSearchSeconds)
+	viper.SetDefault(keyKeyframeSnap, keyframeSnap)
+	viper.SetDefault(keyStreamTypes, streamTypes)
+	viper.SetDefault(keyCoverSearch, coverSearchSeconds)
+	viper.SetDefault(keyThumbnailInterval, thumbnailIntervalSeconds)
+	viper.SetDefault(keyThumbnailMaxCount, thumbnailMaxCount)
+	viper.SetDefault(keyStagedTTLMinutes, stagedTTLMinutes)
+	viper.SetDefault(keyPrivacyScrub, privacyScrub)
+	viper.SetDefault(keyRetentionMaxAge, retentionMaxAgeHours)
+	viper.SetDefault(keyRetentionMaxSize, retentionMaxOutputSize)
+	viper.SetDefault(keyDeleteAfterDownload, deleteAfterDownload)
+	viper.SetDefault(keyRetentionInterval, retentionCheckMinutes)
+	viper.SetDefault(keyMinFreeSpace, minFreeSpace)
+	viper.SetDefault(keySessionSecret, sessionSecret)
+	viper.SetDefault(keyAdminToken, adminToken)
+	viper.SetDefault(keyAuthPasswordHash, authPasswordHash)
+	viper.SetDefault(keyAuthTokenHashes, authTokenHashes)
+	viper.SetDefault(keyAuthMaxAttempts, authMaxAttempts)
+	viper.SetDefault(keyAuthLockoutMinutes, authLockoutMinutes)
+	viper.SetDefault(keyAuthLoginDays, authLoginDays)
+	viper.SetDefault(keyPairingTokenMinutes, pairingTokenMinutes)
+	viper.SetDefault(keyBindInterface, bindInterface)
+	viper.SetDefault(keyAdvertiseHost, advertiseHost)
+	viper.SetDefault(keyMDNSEnabled, mdnsEnabled)
+	viper.SetDefault(keyMDNSHostname, mdnsHostname)
 
 	if err := viper.ReadInConfig(); err != nil {
 		// 如果配置文件不存在则使用默认值
@@ -80,12 +203,24 @@ func readConfig() {
 		outputDir = v
 	}
 
-	if v := viper.GetInt(keyHeadTrimSeconds); v >= 0 {
-		headTrimSeconds = v
+	if v := viper.GetString(keyReferenceDir); v != "" {
+		referenceDir = v
 	}
 
-	if v := viper.GetInt(keyTailSeconds); v >= 0 {
-		tailSeconds = v
+	if v := viper.GetString(keyStateDir); v != "" {
+		stateDir = v
+	}
+
+	if v, err := parseTimecode(viper.GetString(keyHeadTrimSeconds)); err == nil {
+		headTrim = v
+	} else {
+		log.Printf("%s 配置无效, 使用默认值 %s: %v", keyHeadTrimSeconds, formatSeconds(headTrim), err)
+	}
+
+	if v, err := parseTimecode(viper.GetString(keyTailSeconds)); err == nil {
+		tailTrim = v
+	} else {
+		log.Printf("%s 配置无效, 使用默认值 %s: %v", keyTailSeconds, formatSeconds(tailTrim), err)
 	}
 
 	if v := viper.GetString(keyServerPort); v != "" {
@@ -107,4 +242,136 @@ func readConfig() {
 	if v := viper.GetInt(keyIdleTimeoutSeconds); v >= 0 {
 		idleTimeoutSeconds = v
 	}
+
+	if v := viper.GetInt(keyWorkerCount); v > 0 {
+		workerCount = v
+	}
+
+	if v := viper.GetInt(keyQueueSize); v > 0 {
+		queueSize = v
+	}
+
+	if v := viper.GetInt(keyMaxProcessSeconds); v >= 0 {
+		maxProcessSeconds = v
+	}
+
+	if v := viper.GetInt(keyJobTTLMinutes); v > 0 {
+		jobTTLMinutes = v
+	}
+
+	if v := viper.GetInt(keyIntroDetectSeconds); v > 0 {
+		introDetectSeconds = v
+	}
+
+	if v := viper.GetInt(keyHashSampleFPS); v > 0 {
+		hashSampleFPS = v
+	}
+
+	if v := viper.GetInt(keyHashMatchThreshold); v >= 0 && v <= 64 {
+		hashMatchThreshold = v
+	}
+
+	if v := viper.GetInt(keyReferenceSearch); v > 0 {
+		referenceSearchSeconds = v
+	}
+
+	if v := viper.GetInt(keyCoverSearch); v > 0 {
+		coverSearchSeconds = v
+	}
+
+	if v := viper.GetInt(keyThumbnailInterval); v > 0 {
+		thumbnailIntervalSeconds = v
+	}
+
+	if v := viper.GetInt(keyThumbnailMaxCount); v > 0 {
+		thumbnailMaxCount = v
+	}
+
+	if v := viper.GetInt(keyStagedTTLMinutes); v > 0 {
+		stagedTTLMinutes = v
+	}
+
+	privacyScrub = viper.GetBool(keyPrivacyScrub)
+	deleteAfterDownload = viper.GetBool(keyDeleteAfterDownload)
+
+	if v := viper.GetInt(keyRetentionMaxAge); v >= 0 {
+		retentionMaxAgeHours = v
+	}
+
+	if v := viper.GetInt64(keyRetentionMaxSize); v >= 0 {
+		retentionMaxOutputSize = v
+	}
+
+	if v := viper.GetInt(keyRetentionInterval); v > 0 {
+		retentionCheckMinutes = v
+	}
+
+	if v := viper.GetInt64(keyMinFreeSpace); v >= 0 {
+		minFreeSpace = v
+	}
+
+	sessionSecret = viper.GetString(keySessionSecret)
+	adminToken = viper.GetString(keyAdminToken)
+
+	// 只接受有效的 bcrypt 哈希, 避免误把明文密码写入配置
+	if v := viper.GetString(keyAuthPasswordHash); v != "" {
+		if _, err := bcrypt.Cost([]byte(v)); err == nil {
+			authPasswordHash = v
+		} else {
+			log.Printf("%s 不是有效的 bcrypt 哈希, 已忽略: %v", keyAuthPasswordHash, err)
+		}
+	}
+
+	for _, v := range viper.GetStringSlice(keyAuthTokenHashes) {
+		if _, err := bcrypt.Cost([]byte(v)); err == nil {
+			authTokenHashes = append(authTokenHashes, v)
+		} else {
+			log.Printf("%s 中存在无效的 bcrypt 哈希, 已忽略: %v", keyAuthTokenHashes, err)
+		}
+	}
+
+	if v := viper.GetInt(keyAuthMaxAttempts); v > 0 {
+		authMaxAttempts = v
+	}
+
+	if v := viper.GetInt(keyAuthLockoutMinutes); v > 0 {
+		authLockoutMinutes = v
+	}
+
+	if v := viper.GetInt(keyAuthLoginDays); v > 0 {
+		authLoginDays = v
+	}
+
+	if v := viper.GetInt(keyPairingTokenMinutes); v > 0 {
+		pairingTokenMinutes = v
+	}
+
+	bindInterface = strings.TrimSpace(viper.GetString(keyBindInterface))
+
+	if h, p, err := parseAdvertiseHost(viper.GetString(keyAdvertiseHost)); err == nil {
+		advertiseHost, advertisePort = h, p
+	} else {
+		log.Printf("%s 配置无效, 自动选择访问地址: %v", keyAdvertiseHost, err)
+	}
+
+	mdnsEnabled = viper.GetBool(keyMDNSEnabled)
+
+	if v := strings.ToLower(strings.TrimSuffix(viper.GetString(keyMDNSHostname), ".local")); validDNSLabel(v) {
+		mdnsHostname = v
+	} else {
+		log.Printf("%s 配置无效, 使用默认值 %s: %q", keyMDNSHostname, mdnsHostname, v)
+	}
+
+	if v := viper.GetString(keyKeyframeSnap); validSnapMode(v) {
+		keyframeSnap = v
+	} else {
+		log.Printf("%s 配置无效, 使用默认值 %s: %q", keyKeyframeSnap, keyframeSnap, v)
+	}
+
+	// 支持 YAML 列表或逗号分隔的字符串
+	if v, err := parseStreamTypes(viper.GetStringSlice(keyStreamTypes)); err == nil {
+		streamTypes = v
+	} else {
+		log.Printf("%s 配置无效, 使用默认值 %v: %v", keyStreamTypes, streamTypes, err)
+	}
 }
@@ -0,0 +1,226 @@
+//
+// FilePath    : video-trim\cover.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 封面帧: 按时间或自动选择画面, 以附加图片方式写入 MP4/MKV, 不重新编码视频
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 封面帧的选择方式
+const (
+	coverNone = ""     // 不设置封面
+	coverAuto = "auto" // 自动选择与开头画面及同批其他封面差异最大的帧
+	coverAt   = "at"   // 使用指定时间的帧
+)
+
+// parseCover 解析封面参数: 空表示不设置, auto 表示自动选择, 否则为输出视频中的时间
+func parseCover(s string) (string, time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	switch strings.ToLower(s) {
+	case "":
+		return coverNone, 0, nil
+	case coverAuto:
+		return coverAuto, 0, nil
+	}
+
+	d, err := parseTimecode(s)
+	if err != nil {
+		return coverNone, 0, &trimValueError{Field: "cover", Value: s}
+	}
+
+	return coverAt, d, nil
+}
+
+// embedCover 为输出文件设置封面, 返回封面帧的时间和哈希(仅自动选择时有效)
+// avoid 为同一任务中已选择的封面哈希, 自动选择时尽量避开相似的画面
+func embedCover(ctx context.Context, output string, opts trimOptions, avoid []uint64) (time.Duration, uint64, error) {
+	ext := strings.ToLower(filepath.Ext(output))
+	if !isMP4Family(ext) && ext != ".mkv" {
+		return 0, 0, fmt.Errorf("cover is not supported for %s", ext)
+	}
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return 0, 0, fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	at, hash := opts.CoverAt, uint64(0)
+
+	if opts.Cover == coverAuto {
+		at, hash, err = pickCoverFrame(ctx, ffmpegPath, output, avoid)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	base := strings.TrimSuffix(output, filepath.Ext(output))
+
+	coverPath := base + ".cover.jpg"
+	defer os.Remove(coverPath)
+
+	if err := extractCoverFrame(ctx, ffmpegPath, output, at, coverPath); err != nil {
+		return 0, 0, err
+	}
+
+	if err := attachCover(ctx, ffmpegPath, output, coverPath, opts.Scrub); err != nil {
+		return 0, 0, err
+	}
+
+	return at, hash, nil
+}
+
+// pickCoverFrame 在输出视频开头 coverSearchSeconds 秒内每秒抽取一帧, 选择与第一帧及 avoid 差异最大的画面
+// 相册默认显示第一帧, 差异越大越容易区分
+func pickCoverFrame(ctx context.Context, ffmpegPath, input string, avoid []uint64) (time.Duration, uint64, error) {
+	window := time.Duration(coverSearchSeconds) * time.Second
+
+	hashes, err := sampleFrameHashes(ctx, ffmpegPath, input, 0, window, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(hashes) < 2 {
+		return 0, 0, fmt.Errorf("video too short to pick a cover frame")
+	}
+
+	refs := append([]uint64{hashes[0]}, avoid...)
+	best, bestScore := -1, -1
+
+	for i, h := range hashes[1:] {
+		// 纯色画面(黑屏、白屏)不适合作为封面
+		if lowInformationHash(h) {
+			continue
+		}
+
+		// 以与所有参考画面的最小差异作为得分
+		score := 64
+		for _, r := range refs {
+			score = min(score, hammingDistance(h, r))
+		}
+
+		if score > bestScore {
+			best, bestScore = i+1, score
+		}
+	}
+
+	if best < 0 {
+		return 0, 0, fmt.Errorf("no suitable cover frame found")
+	}
+
+	return framesToDuration(best, 1), hashes[best], nil
+}
+
+// extractCoverFrame 从 input 的 at 位置截取一帧保存为 JPEG
+func extractCoverFrame(ctx context.Context, ffmpegPath, input string, at time.Duration, coverPath string) error {
+	cmd := newCommand(ctx, ffmpegPath,
+		"-nostdin", "-v", "error",
+		"-ss", formatFFmpegTime(at),
+		"-i", input,
+		"-map", "0:v:0",
+		"-frames:v", "1",
+		"-q:v", "2",
+		"-y", coverPath,
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("extract cover frame: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// attachCover 以流复制方式重新封装 output 并写入封面, 已有的封面会被替换
+// MP4/MOV 使用 attached_pic 视频流, MKV 使用名为 cover.jpg 的附件
+func attachCover(ctx context.Context, ffmpegPath, output, coverPath string, scrub bool) error {
+	streams, err := probeStreams(ctx, output)
+	if err != nil {
+		return err
+	}
+
+	ext := strings.ToLower(filepath.Ext(output))
+	mkv := ext == ".mkv"
+
+	args := []string{"-nostdin", "-v", "error", "-i", output}
+	if !mkv {
+		args = append(args, "-i", coverPath)
+	}
+
+	var videos, attachments int
+
+	for _, s := range streams {
+		// 跳过原有的封面
+		if s.Disposition.AttachedPic == 1 || (s.CodecType == streamAttachment && isCoverFilename(s.Tags.Filename)) {
+			continue
+		}
+
+		switch s.CodecType {
+		case streamVideo:
+			videos++
+		case streamAttachment:
+			attachments++
+		}
+
+		args = append(args, "-map", "0:"+strconv.Itoa(s.Index))
+	}
+
+	args = append(args, "-c", "copy")
+	args = append(args, metadataArgs(ext, mediaMetadata{}, time.Time{})...)
+
+	// 隐私清理时同样不写入 ffmpeg 的 encoder 标签
+	if scrub {
+		args = append(args, "-fflags", "+bitexact")
+	}
+
+	if mkv {
+		t := "-metadata:s:t:" + strconv.Itoa(attachments)
+		args = append(args, "-attach", coverPath, t, "mimetype=image/jpeg", t, "filename=cover.jpg")
+	} else {
+		args = append(args, "-map", "1:0", "-disposition:v:"+strconv.Itoa(videos), "attached_pic")
+	}
+
+	// 先写入临时文件, 成功后再替换原输出
+	tmp := strings.TrimSuffix(output, filepath.Ext(output)) + ".cover" + filepath.Ext(output)
+	args = append(args, "-y", tmp)
+
+	cmd := newCommand(ctx, ffmpegPath, args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("attach cover: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	if err := os.Rename(tmp, output); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// isMP4Family 判断是否为 MP4/MOV 系列容器
+func isMP4Family(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".mp4", ".m4v", ".mov":
+		return true
+	}
+
+	return false
+}
+
+// isCoverFilename 判断 MKV 附件是否为封面(Matroska 约定的 cover.jpg/cover.png 等)
+func isCoverFilename(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "cover.")
+}
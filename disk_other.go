@@ -0,0 +1,18 @@
+//
+// FilePath    : video-trim\disk_other.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 其他系统下的磁盘空间查询
+//
+
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import "errors"
+
+// getDiskUsage 不支持的系统上返回错误, 调用方跳过空间检查
+func getDiskUsage(string) (diskUsage, error) {
+	return diskUsage{}, errors.ErrUnsupported
+}
@@ -0,0 +1,25 @@
+//
+// FilePath    : video-trim\disk_unix.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 类 Unix 系统下的磁盘空间查询
+//
+
+//go:build linux || darwin || freebsd
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// getDiskUsage 返回 path 所在磁盘的总大小和当前用户可用的空间
+func getDiskUsage(path string) (diskUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return diskUsage{}, err
+	}
+
+	bsize := uint64(st.Bsize)
+
+	return diskUsage{Total: uint64(st.Blocks) * bsize, Free: uint64(st.Bavail) * bsize}, nil
+}
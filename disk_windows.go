@@ -0,0 +1,35 @@
+//
+// FilePath    : video-trim\disk_windows.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : Windows 系统下的磁盘空间查询
+//
+
+package main
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// getDiskUsage 返回 path 所在磁盘的总大小和当前用户可用的空间(考虑磁盘配额)
+func getDiskUsage(path string) (diskUsage, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return diskUsage{}, err
+	}
+
+	p, err := windows.UTF16PtrFromString(abs)
+	if err != nil {
+		return diskUsage{}, err
+	}
+
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return diskUsage{}, err
+	}
+
+	return diskUsage{Total: total, Free: free}, nil
+}
@@ -0,0 +1,85 @@
+//
+// FilePath    : video-trim\diskspace.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 磁盘空间预检: 接收上传前确认上传目录和输出目录所在磁盘有足够的可用空间
+//
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// uploadSpaceFactor 上传需要的可用空间倍数: 上传文件本身加上大小相近的输出文件
+const uploadSpaceFactor = 2
+
+// diskUsage 磁盘空间(字节)
+type diskUsage struct {
+	Total uint64 // 总大小
+	Free  uint64 // 当前用户可用的空间
+}
+
+// UsedPercent 返回已使用空间的百分比
+func (d diskUsage) UsedPercent() int {
+	if d.Total == 0 {
+		return 0
+	}
+
+	return int((d.Total - min(d.Free, d.Total)) * 100 / d.Total)
+}
+
+// workDiskUsage 返回上传目录和输出目录所在磁盘中可用空间较少的一个
+// 两个目录通常在同一磁盘上; 无法查询(如不支持的系统)时返回错误
+func workDiskUsage() (diskUsage, error) {
+	up, err := getDiskUsage(uploadDir)
+	if err != nil {
+		return diskUsage{}, err
+	}
+
+	out, err := getDiskUsage(outputDir)
+	if err != nil {
+		return diskUsage{}, err
+	}
+
+	if out.Free < up.Free {
+		return out, nil
+	}
+
+	return up, nil
+}
+
+// checkDiskSpaceOrRespond 确认写入 need 字节后磁盘仍保留 minFreeSpace 的可用空间, 不足时响应 507
+// 无法查询磁盘空间时只记录日志, 不阻止上传
+func checkDiskSpaceOrRespond(w http.ResponseWriter, r *http.Request, need int64) bool {
+	usage, err := workDiskUsage()
+	if err != nil {
+		log.Printf("check disk space error: %v", err)
+		return true
+	}
+
+	required := uint64(max(need, 0)) + uint64(minFreeSpace)
+	if usage.Free >= required {
+		return true
+	}
+
+	log.Printf("insufficient disk space: need %s, free %s", humanReadableBytes(int64(required)), humanReadableBytes(int64(usage.Free)))
+
+	msg := fmt.Sprintf(getLocale(detectLangFromRequest(r))[KeyInsufficientStorage], humanReadableBytes(int64(required)), humanReadableBytes(int64(usage.Free)))
+	http.Error(w, msg, http.StatusInsufficientStorage)
+
+	return false
+}
+
+// diskUsageHint 返回上传页面显示的磁盘空间提示, 无法查询时返回空字符串
+func diskUsageHint(i18n map[string]string) string {
+	usage, err := workDiskUsage()
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf(i18n[KeyDiskUsageHint], humanReadableBytes(int64(usage.Free)), humanReadableBytes(int64(usage.Total)), usage.UsedPercent())
+}
@@ -0,0 +1,25 @@
+//
+// FilePath    : video-trim\exec_unix.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 类 Unix 系统下的外部命令进程组管理
+//
+
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让命令在独立的进程组中运行, 取消时结束整个进程组
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// 负的 pid 表示向整个进程组发送信号
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
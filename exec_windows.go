@@ -0,0 +1,27 @@
+//
+// FilePath    : video-trim\exec_windows.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : Windows 系统下的外部命令进程树管理
+//
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup 取消时使用 taskkill 结束整个进程树, 失败则直接结束进程
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		// #nosec G204 -- pid 来自当前进程启动的子进程
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+
+		return nil
+	}
+}
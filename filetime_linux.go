@@ -0,0 +1,32 @@
+//
+// FilePath    : video-trim\filetime_linux.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : Linux 系统下文件进入目录的时间
+//
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileAddedTime 返回文件的创建时间(statx 的 btime), 重命名和修改权限不会改变它
+// 输出文件的修改时间被设为拍摄时间, 不能用于判断文件的保存时长; 文件系统不支持创建时间时退回到最近一次状态变化的时间(ctime)
+func fileAddedTime(path string, info os.FileInfo) time.Time {
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx); err == nil && stx.Mask&unix.STATX_BTIME != 0 {
+		return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+	}
+
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix())
+	}
+
+	return info.ModTime()
+}
@@ -0,0 +1,21 @@
+//
+// FilePath    : video-trim\filetime_other.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 其他系统下文件进入目录的时间
+//
+
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// fileAddedTime 无法读取创建时间的系统上使用修改时间
+func fileAddedTime(_ string, info os.FileInfo) time.Time {
+	return info.ModTime()
+}
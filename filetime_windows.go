@@ -0,0 +1,25 @@
+//
+// FilePath    : video-trim\filetime_windows.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : Windows 系统下文件进入目录的时间
+//
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileAddedTime 返回文件的创建时间
+// 输出文件的修改时间被设为拍摄时间, 不能用于判断文件的保存时长
+func fileAddedTime(_ string, info os.FileInfo) time.Time {
+	if d, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, d.CreationTime.Nanoseconds())
+	}
+
+	return info.ModTime()
+}
@@ -9,14 +9,16 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // handleHome 处理首页请求, 渲染上传页面
@@ -33,25 +35,49 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 已登记的参考片段, 读取失败时仅记录日志
+	references, err := loadReferenceClips()
+	if err != nil {
+		log.Printf("load reference clips error: %v", err)
+	}
+
+	// 默认保留的流类型, 用于勾选上传页面的复选框
+	keepStreams := map[string]bool{}
+	for _, t := range streamTypes {
+		keepStreams[t] = true
+	}
+
 	// 选择语言并加载翻译
 	lang := detectLangFromRequest(r)
 	i18n := getLocale(lang)
 	data := struct {
-		Head              int
-		Tail              int
+		Head              string
+		Tail              string
 		MaxUpload         int64
 		MaxUploadReadable string
+		DiskUsage         string
 		I18n              map[string]string
 		AvailableLocales  []LocaleMeta
 		Lang              string
+		References        []*referenceClip
+		Snap              string
+		KeepStreams       map[string]bool
+		Scrub             bool
+		Logout            bool
 	}{
-		Head:              headTrimSeconds,
-		Tail:              tailSeconds,
+		Head:              formatSeconds(headTrim),
+		Tail:              formatSeconds(tailTrim),
 		MaxUpload:         maxUploadSize,
 		MaxUploadReadable: humanReadableBytes(maxUploadSize),
+		DiskUsage:         diskUsageHint(i18n),
 		I18n:              i18n,
 		AvailableLocales:  GetAvailableLocales(lang),
 		Lang:              lang,
+		References:        references,
+		Snap:              keyframeSnap,
+		KeepStreams:       keepStreams,
+		Scrub:             privacyScrub,
+		Logout:            authPasswordHash != "",
 	}
 
 	// 执行模板并写入响应
@@ -70,13 +96,34 @@ func handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 解析 multipart 表单
-	if !parseMultipartFormOrRespond(w, r) {
+	// 接收前确认磁盘空间足够保存上传文件和输出文件
+	if !checkDiskSpaceOrRespond(w, r, uploadSpaceFactor*r.ContentLength) {
+		return
+	}
+
+	// 每个请求使用独立的工作目录, 同时上传的同名文件不会互相覆盖
+	jobID := newJobID()
+	workDir := jobUploadDir(jobID)
+
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		log.Printf("create job workspace error: %v", err)
+		http.Error(w, getLocale(detectLangFromRequest(r))[KeyUploadFailed]+err.Error(), http.StatusInternalServerError)
+
 		return
 	}
 
-	// 获取上传的文件列表
-	files, ok := getUploadedFilesOrRespond(w, r)
+	// 任务未提交时删除整个工作目录(包括已保存的上传文件和移入的 tus 上传)
+	submitted := false
+	defer func() {
+		if !submitted {
+			removeJobWorkspace(jobID)
+		}
+	}()
+
+	// 流式读取表单, 上传文件直接写入工作目录, 同时检查大小和魔法数字
+	form, ok := readUploadFormOrRespond(w, r, "videos", func(idx int, filename string) string {
+		return filepath.Join(workDir, fmt.Sprintf("input_%d%s", idx, uploadExt(filename)))
+	})
 	if !ok {
 		return
 	}
@@ -84,31 +131,197 @@ func handleUpload(w http.ResponseWriter, r *http.Request) {
 	// 选择语言并加载翻译
 	lang := detectLangFromRequest(r)
 
-	// 检查每个文件大小是否超出限制
-	if !checkFilesSizeOrRespond(w, files, lang) {
+	// 已在时间轴上预览过的文件暂存在服务器上, 只提交其 ID; 先取出, 同一暂存文件不会被两个任务同时使用
+	staged, ok := takeStagedFilesOrRespond(w, r, lang)
+	if !ok {
+		return
+	}
+
+	// 任务未提交时(参数错误、队列已满等)放回暂存文件, 仍可继续预览和提交
+	defer func() {
+		if !submitted {
+			staging.Restore(staged)
+		}
+	}()
+
+	// 通过 tus 断点续传上传完成的文件, 只提交其 ID
+	tusUploads, ok := getTusUploadsOrRespond(w, r, lang)
+	if !ok {
 		return
 	}
 
-	// 魔法数字校验, 确保上传文件看起来像视频文件
-	if !checkFilesMagicOrRespond(w, files, lang) {
+	// 只提交暂存文件或 tus 上传时可以没有上传文件
+	if len(staged) == 0 && len(tusUploads) == 0 && len(form.Files) == 0 {
+		respondSelectAtLeastOne(w, r)
 		return
 	}
 
-	// 解析要剪掉的秒数和去尾秒数
-	headSec := parseHeadSec(r)
-	tailSec := parseTailSec(r)
+	// 暂存文件和 tus 上传已在磁盘上, 只需为输出文件预留空间
+	var pending int64
+	for _, sf := range staged {
+		pending += sf.Size
+	}
 
-	// 如果 head 和 tail 都为 0, 则无需处理
-	if headSec == 0 && tailSec == 0 {
+	for _, u := range tusUploads {
+		pending += u.Length
+	}
+
+	if !checkDiskSpaceOrRespond(w, r, pending) {
+		return
+	}
+
+	// 解析掐头和去尾时长, 格式错误时直接提示
+	opts, err := parseTrimOptions(r)
+	if err != nil {
+		respondInvalidTrimValue(w, err, lang)
+		return
+	}
+
+	// 暂存文件在时间轴上选择的入点/出点
+	stagedFiles := make([]*JobFile, 0, len(staged))
+	pickedTrim := false
+
+	for _, sf := range staged {
+		head, tail, err := stagedTrim(r, sf)
+		if err != nil {
+			respondInvalidTrimValue(w, err, lang)
+			return
+		}
+
+		pickedTrim = pickedTrim || head != nil || tail != nil
+
+		stagedFiles = append(stagedFiles, &JobFile{
+			Name:      sf.Name,
+			State:     JobQueued,
+			Head:      head,
+			Tail:      tail,
+			inputPath: sf.path,
+			modTime:   sf.modTime,
+		})
+	}
+
+	// 不裁剪(head 和 tail 都为 0 且没有区间列表)、不设置封面、不清理元数据、保留全部流, 也没有在时间轴上选择入点/出点, 则无需处理
+	if !opts.needsProcessing() && !pickedTrim {
 		respondNoTrim(w, r)
 		return
 	}
 
-	// 逐个处理文件并生成响应
-	processed := processUploadedFiles(files, headSec, tailSec)
+	// 创建任务, 交由工作池异步处理
+	job := newJobFromUploads(jobID, form.Files, r.PostForm["last_modified"], opts)
+	job.owner = requestSession(r)
+
+	// 移动 tus 上传之前预留队列位置, 队列已满时上传仍保留在服务器上, 可以稍后再次提交
+	if err := jobs.Reserve(); err != nil {
+		http.Error(w, getLocale(lang)[KeyQueueFull], http.StatusServiceUnavailable)
+		return
+	}
+
+	// tus 上传移动为任务的输入文件, 修改时间来自上传时的元数据
+	tusFiles, err := takeTusUploads(jobID, tusUploads)
+	if err != nil {
+		jobs.Release()
+		log.Printf("take tus uploads error: %v", err)
+		http.Error(w, getLocale(lang)[KeyUploadFailed]+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	for i, u := range tusFiles {
+		job.Files = append(job.Files, &JobFile{
+			Name:      u.Filename,
+			State:     JobQueued,
+			inputPath: u.Path,
+			modTime:   parseLastModified(tusUploads[i].Metadata["lastModified"]),
+		})
+	}
+
+	job.Files = append(job.Files, stagedFiles...)
+
+	jobs.Submit(job)
+	submitted = true
+
+	// 暂存文件已交由任务处理, 不再需要缩略图
+	for _, sf := range staged {
+		sf.removeSprite()
+	}
+
+	// 脚本调用返回任务 JSON, 浏览器返回可轮询的结果页面
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusAccepted, job.Snapshot())
+		return
+	}
+
+	generateResponse(w, job, r)
+}
+
+// takeStagedFilesOrRespond 根据表单中的 staged 字段取出暂存文件, 不存在或已被其他请求取出时放回已取出的文件并直接响应
+func takeStagedFilesOrRespond(w http.ResponseWriter, r *http.Request, lang string) ([]*stagedFile, bool) {
+	staged := []*stagedFile{}
+
+	for _, id := range r.PostForm["staged"] {
+		f, ok := staging.Get(id)
+		if !ok || !canAccess(r, f.owner) {
+			staging.Restore(staged)
+			http.Error(w, fmt.Sprintf(getLocale(lang)[KeyStagedNotFound], id), http.StatusBadRequest)
+
+			return nil, false
+		}
+
+		// Get 之后可能已被同时提交的请求取出
+		if _, ok := staging.Take(id); !ok {
+			staging.Restore(staged)
+			http.Error(w, fmt.Sprintf(getLocale(lang)[KeyStagedInUse], id), http.StatusConflict)
+
+			return nil, false
+		}
+
+		staged = append(staged, f)
+	}
+
+	return staged, true
+}
+
+// newJobFromUploads 为已保存到 uploadDir 的上传文件创建对应的任务
+// modTimes 为浏览器按文件顺序提供的修改时间(Unix 毫秒), 可以缺省
+func newJobFromUploads(id string, files []*uploadedFile, modTimes []string, opts trimOptions) *Job {
+	job := newJob(id, opts)
+
+	for idx, u := range files {
+		f := &JobFile{
+			Name:      u.Filename,
+			State:     JobQueued,
+			inputPath: u.Path,
+		}
+
+		if idx < len(modTimes) {
+			f.modTime = parseLastModified(modTimes[idx])
+		}
+
+		job.Files = append(job.Files, f)
+	}
+
+	return job
+}
+
+// getJobOrRespond 根据路径中的 ID 查找任务, 不存在或不属于本会话时响应 404
+func getJobOrRespond(w http.ResponseWriter, r *http.Request) (*Job, bool) {
+	job, ok := jobs.Get(r.PathValue("id"))
+	if !ok || !canAccess(r, job.owner) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	return job, true
+}
+
+// handleJob 返回指定任务的状态及每个文件的处理结果(JSON)
+func handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := getJobOrRespond(w, r)
+	if !ok {
+		return
+	}
 
-	// 生成并返回下载页面
-	generateResponse(w, processed, r)
+	writeJSON(w, http.StatusOK, job.Snapshot())
 }
 
 // detectLangFromRequest 返回请求中优先级为: ?lang -> cookie(lang) -> defaultLang 的语言代码
@@ -127,6 +340,42 @@ func detectLangFromRequest(r *http.Request) string {
 	return lang
 }
 
+// respondInvalidTrimValue 使用 i18n 输出时间格式错误提示
+func respondInvalidTrimValue(w http.ResponseWriter, err error, lang string) {
+	i18n := getLocale(lang)
+	msg := i18n[KeyRequestParseError]
+
+	var tve *trimValueError
+	if errors.As(err, &tve) {
+		label, format := i18n[KeyHeadLabel], i18n[KeyInvalidTrimValue]
+
+		switch tve.Field {
+		case "tail":
+			label = i18n[KeyTailLabel]
+		case "cuts":
+			label = i18n[KeyCutsLabel]
+		case "cut_mode":
+			label, format = i18n[KeyCutsLabel], i18n[KeyInvalidOption]
+		case "trim_mode":
+			format = i18n[KeyInvalidOption]
+		case "snap":
+			label, format = i18n[KeySnapLabel], i18n[KeyInvalidOption]
+		case "scrub":
+			label, format = i18n[KeyScrubLabel], i18n[KeyInvalidOption]
+		case "streams":
+			label, format = i18n[KeyStreamsLabel], i18n[KeyInvalidOption]
+		case "cover":
+			label = i18n[KeyCoverLabel]
+		case "in", "out":
+			label = i18n[KeyTimeline]
+		}
+
+		msg = fmt.Sprintf(format, tve.Value, label)
+	}
+
+	http.Error(w, msg, http.StatusBadRequest)
+}
+
 // respondNoTrim 使用 i18n 输出 JS alert 并重定向回首页
 func respondNoTrim(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -139,21 +388,83 @@ func respondNoTrim(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "<script>alert('%s');location.href='/';</script>", esc)
 }
 
-// processUploadedFiles 逐个调用 processSingleFile 并返回成功的输出文件名列表
-func processUploadedFiles(files []*multipart.FileHeader, headSec, tailSec int) []string {
-	processed := []string{}
+// handleJobCancel 取消任务: 结束正在运行的 ffmpeg 并清理临时输入文件
+func handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	job, ok := getJobOrRespond(w, r)
+	if !ok {
+		return
+	}
 
-	for idx, hdr := range files {
-		outName, err := processSingleFile(hdr, idx, headSec, tailSec)
-		if err != nil {
-			log.Printf("process file %s error: %v", hdr.Filename, err)
-			continue
+	// 已结束的任务无法取消
+	if !job.Cancel() {
+		writeJSON(w, http.StatusConflict, job.Snapshot())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job.Snapshot())
+}
+
+// handleJobEvents 通过 Server-Sent Events 推送任务状态和每个文件的处理进度
+func handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := getJobOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	// SSE 为长连接, 取消服务器的写入超时
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("clear write deadline error: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// 心跳间隔, 防止代理或浏览器因空闲断开连接
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		// 先获取通知通道再读取快照, 确保不会错过两者之间的更新
+		changed := job.Changed()
+
+		if err := writeSSE(w, "job", job.Snapshot()); err != nil {
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		if job.Finished() {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+			// 合并短时间内的多次更新, 降低推送频率
+			time.Sleep(200 * time.Millisecond)
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
 		}
+	}
+}
 
-		processed = append(processed, outName)
+// writeSSE 以 Server-Sent Events 格式写出一条 JSON 事件
+func writeSSE(w http.ResponseWriter, event string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
 	}
 
-	return processed
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+
+	return err
 }
 
 // saveFile 处理文件下载请求
@@ -162,17 +473,42 @@ func handleDownload(w http.ResponseWriter, r *http.Request) {
 	// 使用 filepath.Base 防止路径遍历
 	filename := filepath.Base(strings.TrimPrefix(r.URL.Path, "/download/"))
 
+	// 隐藏文件和目录(如任务工作目录)不提供下载
+	if !validLibraryName(filename) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 只能下载本会话生成的文件
+	if !canAccess(r, outputOwners.Owner(filename)) {
+		http.NotFound(w, r)
+		return
+	}
+
 	filePath := filepath.Join(outputDir, filename)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) || (err == nil && !info.Mode().IsRegular()) {
 		http.NotFound(w, r)
 		return
 	}
 
 	// 使用标准库直接提供文件下载
-	http.ServeFile(w, r, filePath)
+	if !deleteAfterDownload || err != nil {
+		http.ServeFile(w, r, filePath)
+		return
+	}
+
+	// 完整下载(而不是播放器的分段请求)完成后删除文件
+	cw := &countingResponseWriter{ResponseWriter: w}
+	http.ServeFile(cw, r, filePath)
+
+	if r.Method == http.MethodGet && cw.status == http.StatusOK && cw.n == info.Size() {
+		expireOutput(filename, "downloaded")
+	}
 }
 
-// 处理清理 uploads 和 outputs 目录下的所有内容(保留目录)
+// handleClear 清理本会话上传和生成的文件; 管理员可通过 all=1 清理 uploads 和 outputs 目录下的所有内容(保留目录)
 func handleClear(w http.ResponseWriter, r *http.Request) {
 	// 仅允许 POST 请求触发清理操作
 	if r.Method != "POST" {
@@ -180,6 +516,29 @@ func handleClear(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.FormValue("all") != "1" {
+		clearSessionFiles(requestSession(r))
+
+		// 清理完成后重定向回首页
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		return
+	}
+
+	if !isAdmin(r) {
+		http.Error(w, getLocale(detectLangFromRequest(r))[KeyAdminRequired], http.StatusForbidden)
+		return
+	}
+
+	// 先取消任务并取出暂存文件和断点续传上传, 内存中不再保留指向已删除文件的记录
+	canceled := jobs.CancelAll()
+
+	for _, f := range staging.TakeAll() {
+		f.removeSprite()
+	}
+
+	removeTusUploads(func(*tusUpload) bool { return true })
+
 	// 遍历 uploads 和 outputs 目录, 删除所有子项但保留目录本身
 	dirs := []string{uploadDir, outputDir}
 	for _, d := range dirs {
@@ -190,6 +549,11 @@ func handleClear(w http.ResponseWriter, r *http.Request) {
 		}
 
 		for _, e := range entries {
+			// 运行中的任务已取消, 由 worker 在 ffmpeg 退出后删除其工作目录
+			if activeJobWorkspace(e.Name()) {
+				continue
+			}
+
 			p := filepath.Join(d, e.Name())
 			if err := os.RemoveAll(p); err != nil {
 				log.Printf("remove %s error: %v", p, err)
@@ -197,6 +561,22 @@ func handleClear(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	outputOwners.Reset()
+
+	log.Printf("clear: removed all files, canceled %d jobs", canceled)
+
 	// 清理完成后重定向回首页
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+// activeJobWorkspace 判断目录项是否为尚未结束的任务的工作目录
+func activeJobWorkspace(name string) bool {
+	for _, prefix := range []string{jobUploadPrefix, jobWorkPrefix} {
+		if id, ok := strings.CutPrefix(name, prefix); ok {
+			job, found := jobs.Get(id)
+			return found && !job.Finished()
+		}
+	}
+
+	return false
+}
@@ -39,4 +39,104 @@ const (
 	KeyNotSupportedVideo     = "NotSupportedVideo"
 	KeyUploadError           = "UploadError"
 	KeyUploadFailed          = "UploadFailed"
+	KeyQueueFull             = "QueueFull"
+	KeyStateQueued           = "StateQueued"
+	KeyStateRunning          = "StateRunning"
+	KeyStateDone             = "StateDone"
+	KeyStateFailed           = "StateFailed"
+	KeyETA                   = "ETA"
+	KeyStateCanceled         = "StateCanceled"
+	KeyCancelJob             = "CancelJob"
+	KeyInvalidTrimValue      = "InvalidTrimValue"
+	KeyTimeFormatHint        = "TimeFormatHint"
+	KeyCutsLabel             = "CutsLabel"
+	KeyCutModeRemove         = "CutModeRemove"
+	KeyCutModeKeep           = "CutModeKeep"
+	KeyTrimModeFixed         = "TrimModeFixed"
+	KeyTrimModeIntro         = "TrimModeIntro"
+	KeyDetectedIntro         = "DetectedIntro"
+	KeyTrimModeReference     = "TrimModeReference"
+	KeyReferenceClips        = "ReferenceClips"
+	KeyReferenceName         = "ReferenceName"
+	KeyReferenceAdd          = "ReferenceAdd"
+	KeyReferenceEmpty        = "ReferenceEmpty"
+	KeyInvalidReferenceName  = "InvalidReferenceName"
+	KeyReferenceExists       = "ReferenceExists"
+	KeyReferenceFailed       = "ReferenceFailed"
+	KeyDelete                = "Delete"
+	KeyMatchedIntro          = "MatchedIntro"
+	KeyMatchedOutro          = "MatchedOutro"
+	KeySnapLabel             = "SnapLabel"
+	KeySnapBack              = "SnapBack"
+	KeySnapForward           = "SnapForward"
+	KeySnapNearest           = "SnapNearest"
+	KeyActualCuts            = "ActualCuts"
+	KeyRequestedCuts         = "RequestedCuts"
+	KeySnapPrecise           = "SnapPrecise"
+	KeyStreamsLabel          = "StreamsLabel"
+	KeyStreamVideo           = "StreamVideo"
+	KeyStreamAudio           = "StreamAudio"
+	KeyStreamSubtitle        = "StreamSubtitle"
+	KeyStreamAttachment      = "StreamAttachment"
+	KeyStreamData            = "StreamData"
+	KeyInvalidOption         = "InvalidOption"
+	KeyCoverLabel            = "CoverLabel"
+	KeyCoverHint             = "CoverHint"
+	KeyCoverAt               = "CoverAt"
+	KeyTimeline              = "Timeline"
+	KeyStagedNotFound        = "StagedNotFound"
+	KeyStagingText           = "StagingText"
+	KeyTimelineFailed        = "TimelineFailed"
+	KeyInPoint               = "InPoint"
+	KeyOutPoint              = "OutPoint"
+	KeyCapturedAt            = "CapturedAt"
+	KeyScrubLabel            = "ScrubLabel"
+	KeyScrubHint             = "ScrubHint"
+	KeyScrubbedTags          = "ScrubbedTags"
+	KeyTusNotFound           = "TusNotFound"
+	KeyUploadResumable       = "UploadResumable"
+	KeyLibraryTitle          = "LibraryTitle"
+	KeyLibraryLink           = "LibraryLink"
+	KeyLibrarySearch         = "LibrarySearch"
+	KeyLibrarySortNewest     = "LibrarySortNewest"
+	KeyLibrarySortOldest     = "LibrarySortOldest"
+	KeyLibrarySortName       = "LibrarySortName"
+	KeyLibrarySortSize       = "LibrarySortSize"
+	KeyLibrarySortDuration   = "LibrarySortDuration"
+	KeyLibraryEmpty          = "LibraryEmpty"
+	KeyLibraryPrev           = "LibraryPrev"
+	KeyLibraryNext           = "LibraryNext"
+	KeyLibraryRename         = "LibraryRename"
+	KeyLibraryRenamePrompt   = "LibraryRenamePrompt"
+	KeyLibraryInvalidName    = "LibraryInvalidName"
+	KeyLibraryFileExists     = "LibraryFileExists"
+	KeyLibraryExtMismatch    = "LibraryExtMismatch"
+	KeyInsufficientStorage   = "InsufficientStorage"
+	KeyMaxUploadHint         = "MaxUploadHint"
+	KeyDiskUsageHint         = "DiskUsageHint"
+	KeyAdminRequired         = "AdminRequired"
+	KeyAuthRequired          = "AuthRequired"
+	KeyLoginTitle            = "LoginTitle"
+	KeyLoginPassword         = "LoginPassword"
+	KeyLoginButton           = "LoginButton"
+	KeyLoginFailed           = "LoginFailed"
+	KeyLoginLocked           = "LoginLocked"
+	KeyLoginDisabled         = "LoginDisabled"
+	KeyLogout                = "Logout"
+	KeyPairLink              = "PairLink"
+	KeyPairTitle             = "PairTitle"
+	KeyPairHint              = "PairHint"
+	KeyPairInvalid           = "PairInvalid"
+	KeyDevicesTitle          = "DevicesTitle"
+	KeyDevicesEmpty          = "DevicesEmpty"
+	KeyDevicePairedAt        = "DevicePairedAt"
+	KeyDeviceLastSeen        = "DeviceLastSeen"
+	KeyDeviceRevoke          = "DeviceRevoke"
+	KeyDeviceConfirmRevoke   = "DeviceConfirmRevoke"
+	KeyAdminTokenPrompt      = "AdminTokenPrompt"
+	KeyAdminTokenInvalid     = "AdminTokenInvalid"
+	KeyAdminDisabled         = "AdminDisabled"
+	KeyStagedInUse           = "StagedInUse"
+	KeyNoIntroMatch          = "NoIntroMatch"
+	KeyNoOutroMatch          = "NoOutroMatch"
 )
@@ -15,8 +15,8 @@ var langEN = map[string]string{
 	KeyChooseVideo:           "Choose videos",
 	KeyUploadButton:          "Upload & Process",
 	KeyUploadingText:         "Uploading and processing...",
-	KeyClearButton:           "Clear uploaded and output files",
-	KeyConfirmClear:          "Clear all uploaded and output files? This cannot be undone.",
+	KeyClearButton:           "Clear my uploaded and output files",
+	KeyConfirmClear:          "Clear all files uploaded and generated in this browser? This cannot be undone.",
 	KeySelectAtLeastOne:      "Please select at least one video file before uploading",
 	KeyFileTooLargePrefix:    "File \"",
 	KeyFileTooLargeSuffix:    "\" exceeds allowed size ",
@@ -29,7 +29,7 @@ var langEN = map[string]string{
 	KeyDownload:              "Download",
 	KeyReturnUpload:          "Return to Upload",
 	KeyRemove:                "Remove",
-	KeyAlertNoTrim:           "Head and tail trims are both 0, no processing needed",
+	KeyAlertNoTrim:           "Nothing to do: head and tail trims are both 0 and no cut list, cover, privacy scrub or stream selection was requested",
 	KeyNoProcessedFilesHint:  "No files were successfully processed, please check source files or FFmpeg logs.",
 	KeyRequestBodyTooLarge:   "File too large, maximum allowed upload size is %s. Please reduce file size and retry.",
 	KeyRequestParseError:     "Request body too large or unable to parse form",
@@ -38,4 +38,104 @@ var langEN = map[string]string{
 	KeyNotSupportedVideo:     "File %s is not a supported video format (magic number check failed)",
 	KeyUploadError:           "Upload error",
 	KeyUploadFailed:          "Upload failed: ",
+	KeyQueueFull:             "Server is busy, the processing queue is full. Please retry later.",
+	KeyStateQueued:           "Queued",
+	KeyStateRunning:          "Processing",
+	KeyStateDone:             "Done",
+	KeyStateFailed:           "Failed",
+	KeyETA:                   "ETA",
+	KeyStateCanceled:         "Canceled",
+	KeyCancelJob:             "Cancel processing",
+	KeyInvalidTrimValue:      "Invalid time value \"%s\" for \"%s\". Use seconds (e.g. 6.5) or HH:MM:SS.mmm.",
+	KeyTimeFormatHint:        "Seconds (e.g. 6.5) or HH:MM:SS.mmm",
+	KeyCutsLabel:             "Ranges (optional, e.g. 1:00-1:30, 5:10-5:40)",
+	KeyCutModeRemove:         "Remove these ranges",
+	KeyCutModeKeep:           "Keep only these ranges",
+	KeyTrimModeFixed:         "Use the head trim above",
+	KeyTrimModeIntro:         "Auto-detect the common intro (2+ files)",
+	KeyDetectedIntro:         "Detected intro:",
+	KeyTrimModeReference:     "Match registered intro/outro clips",
+	KeyReferenceClips:        "Reference intro/outro clips",
+	KeyReferenceName:         "Clip name (letters, digits, _ or -)",
+	KeyReferenceAdd:          "Add reference clip",
+	KeyReferenceEmpty:        "No reference clips yet.",
+	KeyInvalidReferenceName:  "Invalid clip name: use 1-64 letters, digits, _ or -",
+	KeyReferenceExists:       "A reference clip with this name already exists",
+	KeyReferenceFailed:       "Failed to register reference clip: ",
+	KeyDelete:                "Delete",
+	KeyMatchedIntro:          "Matched intro",
+	KeyMatchedOutro:          "Matched outro",
+	KeySnapLabel:             "Keyframe snap",
+	KeySnapBack:              "Previous keyframe (keep a little more)",
+	KeySnapForward:           "Next keyframe (remove a little more)",
+	KeySnapNearest:           "Nearest keyframe",
+	KeyActualCuts:            "Actual cut",
+	KeyRequestedCuts:         "requested",
+	KeySnapPrecise:           "Frame-accurate (re-encode boundary only)",
+	KeyStreamsLabel:          "Keep streams",
+	KeyStreamVideo:           "Video",
+	KeyStreamAudio:           "Audio",
+	KeyStreamSubtitle:        "Subtitles",
+	KeyStreamAttachment:      "Attachments",
+	KeyStreamData:            "Data",
+	KeyInvalidOption:         "\"%s\" is not a valid choice for %s.",
+	KeyCoverLabel:            "Cover frame",
+	KeyCoverHint:             "Empty = unchanged, auto, or a time such as 0:05",
+	KeyCoverAt:               "Cover at",
+	KeyTimeline:              "Timeline",
+	KeyStagedNotFound:        "Staged file %s no longer exists, please select it again.",
+	KeyStagingText:           "Preparing...",
+	KeyTimelineFailed:        "Could not load the timeline: ",
+	KeyInPoint:               "In",
+	KeyOutPoint:              "Out",
+	KeyCapturedAt:            "Captured",
+	KeyScrubLabel:            "Remove location and device metadata",
+	KeyScrubHint:             "Strips GPS location, device model, encoder and user comment tags; the capture time is kept",
+	KeyScrubbedTags:          "Removed metadata:",
+	KeyTusNotFound:           "Upload %s does not exist or is not complete, please upload it again.",
+	KeyUploadResumable:       "Upload interrupted. Submit again to continue from where it stopped.",
+	KeyLibraryTitle:          "Output library",
+	KeyLibraryLink:           "Browse output library",
+	KeyLibrarySearch:         "Search file name",
+	KeyLibrarySortNewest:     "Newest first",
+	KeyLibrarySortOldest:     "Oldest first",
+	KeyLibrarySortName:       "Name",
+	KeyLibrarySortSize:       "Largest first",
+	KeyLibrarySortDuration:   "Longest first",
+	KeyLibraryEmpty:          "No files in the output directory",
+	KeyLibraryPrev:           "Previous",
+	KeyLibraryNext:           "Next",
+	KeyLibraryRename:         "Rename",
+	KeyLibraryRenamePrompt:   "New file name",
+	KeyLibraryInvalidName:    "Invalid file name",
+	KeyLibraryFileExists:     "A file with this name already exists",
+	KeyLibraryExtMismatch:    "The new name must keep the %s extension",
+	KeyInsufficientStorage:   "Not enough disk space on the server: %s needed, %s available",
+	KeyMaxUploadHint:         "Max file size: %s",
+	KeyDiskUsageHint:         "Server disk: %s free of %s (%d%% used)",
+	KeyAdminRequired:         "Admin token required",
+	KeyAuthRequired:          "Authentication required",
+	KeyLoginTitle:            "Sign in",
+	KeyLoginPassword:         "Password",
+	KeyLoginButton:           "Sign in",
+	KeyLoginFailed:           "Incorrect password",
+	KeyLoginLocked:           "Too many failed attempts, please try again in %d minute(s)",
+	KeyLoginDisabled:         "Password sign-in is not enabled; use an API token (Authorization: Bearer <token>)",
+	KeyLogout:                "Sign out",
+	KeyPairLink:              "Pair phone",
+	KeyPairTitle:             "Pair a phone",
+	KeyPairHint:              "Scan with the phone camera to open this page without typing the address or password. The code works once and expires in %d minute(s).",
+	KeyPairInvalid:           "The pairing code is invalid, already used or expired. Please generate a new one.",
+	KeyDevicesTitle:          "Paired devices",
+	KeyDevicesEmpty:          "No paired devices yet.",
+	KeyDevicePairedAt:        "Paired",
+	KeyDeviceLastSeen:        "last seen",
+	KeyDeviceRevoke:          "Revoke",
+	KeyDeviceConfirmRevoke:   "Revoke this device? It will need to sign in or pair again.",
+	KeyAdminTokenPrompt:      "Admin token",
+	KeyAdminTokenInvalid:     "Incorrect admin token",
+	KeyAdminDisabled:         "Admin pages are disabled. Set admin_token in config.yaml to enable them.",
+	KeyStagedInUse:           "Staged file %s is already being submitted by another request.",
+	KeyNoIntroMatch:          "No reference intro matched, the fixed head trim was used",
+	KeyNoOutroMatch:          "No reference outro matched, the fixed tail trim was used",
 }
@@ -15,8 +15,8 @@ var langZH = map[string]string{
 	KeyChooseVideo:           "选择视频",
 	KeyUploadButton:          "上传并处理",
 	KeyUploadingText:         "正在上传并处理...",
-	KeyClearButton:           "清理已上传与输出文件",
-	KeyConfirmClear:          "确认清理所有已上传和输出文件吗？此操作不可恢复。",
+	KeyClearButton:           "清理我的上传与输出文件",
+	KeyConfirmClear:          "确认清理本浏览器上传和生成的所有文件吗？此操作不可恢复。",
 	KeySelectAtLeastOne:      "请选择至少一个视频文件后再上传",
 	KeyFileTooLargePrefix:    "文件 \"",
 	KeyFileTooLargeSuffix:    "\" 超过单文件允许大小 ",
@@ -29,7 +29,7 @@ var langZH = map[string]string{
 	KeyDownload:              "下载",
 	KeyReturnUpload:          "返回上传页面",
 	KeyRemove:                "移除",
-	KeyAlertNoTrim:           "裁剪开头和结尾均为 0, 无需处理",
+	KeyAlertNoTrim:           "裁剪开头和结尾均为 0, 也没有设置区间、封面、隐私清理或流选择, 无需处理",
 	KeyNoProcessedFilesHint:  "没有文件被成功处理, 请检查源文件或 FFmpeg 日志。",
 	KeyRequestBodyTooLarge:   "文件太大, 最大允许上传大小为 %s。请减少文件大小后重试。",
 	KeyRequestParseError:     "请求体太大或无法解析表单",
@@ -38,4 +38,104 @@ var langZH = map[string]string{
 	KeyNotSupportedVideo:     "文件 %s 不是受支持的视频格式(魔法数字校验失败)",
 	KeyUploadError:           "上传错误",
 	KeyUploadFailed:          "上传失败：",
+	KeyQueueFull:             "服务器繁忙, 处理队列已满, 请稍后重试。",
+	KeyStateQueued:           "排队中",
+	KeyStateRunning:          "处理中",
+	KeyStateDone:             "已完成",
+	KeyStateFailed:           "失败",
+	KeyETA:                   "剩余",
+	KeyStateCanceled:         "已取消",
+	KeyCancelJob:             "取消处理",
+	KeyInvalidTrimValue:      "时间值 \"%s\" 无效(%s), 请输入秒数(如 6.5)或 HH:MM:SS.mmm 格式的时间。",
+	KeyTimeFormatHint:        "秒数(如 6.5)或 HH:MM:SS.mmm",
+	KeyCutsLabel:             "区间列表(可选, 如 1:00-1:30, 5:10-5:40)",
+	KeyCutModeRemove:         "删除这些区间",
+	KeyCutModeKeep:           "只保留这些区间",
+	KeyTrimModeFixed:         "使用上面的掐头时长",
+	KeyTrimModeIntro:         "自动检测共同片头(需 2 个及以上文件)",
+	KeyDetectedIntro:         "检测到片头:",
+	KeyTrimModeReference:     "匹配已登记的片头/片尾片段",
+	KeyReferenceClips:        "参考片头/片尾片段",
+	KeyReferenceName:         "片段名称(字母、数字、_ 或 -)",
+	KeyReferenceAdd:          "添加参考片段",
+	KeyReferenceEmpty:        "暂无参考片段。",
+	KeyInvalidReferenceName:  "片段名称无效: 请使用 1-64 个字母、数字、_ 或 -",
+	KeyReferenceExists:       "同名参考片段已存在",
+	KeyReferenceFailed:       "登记参考片段失败: ",
+	KeyDelete:                "删除",
+	KeyMatchedIntro:          "匹配片头",
+	KeyMatchedOutro:          "匹配片尾",
+	KeySnapLabel:             "关键帧对齐",
+	KeySnapBack:              "之前的关键帧(多保留一点)",
+	KeySnapForward:           "之后的关键帧(多去掉一点)",
+	KeySnapNearest:           "最近的关键帧",
+	KeyActualCuts:            "实际剪切",
+	KeyRequestedCuts:         "请求",
+	KeySnapPrecise:           "精确到帧(只重新编码边界)",
+	KeyStreamsLabel:          "保留的流",
+	KeyStreamVideo:           "视频",
+	KeyStreamAudio:           "音频",
+	KeyStreamSubtitle:        "字幕",
+	KeyStreamAttachment:      "附件",
+	KeyStreamData:            "数据",
+	KeyInvalidOption:         "\"%s\" 不是有效的%s选项。",
+	KeyCoverLabel:            "封面帧",
+	KeyCoverHint:             "留空不修改, auto 自动选择, 或输入时间如 0:05",
+	KeyCoverAt:               "封面位于",
+	KeyTimeline:              "时间轴",
+	KeyStagedNotFound:        "暂存文件 %s 不存在, 请重新选择。",
+	KeyStagingText:           "准备中...",
+	KeyTimelineFailed:        "无法加载时间轴: ",
+	KeyInPoint:               "入点",
+	KeyOutPoint:              "出点",
+	KeyCapturedAt:            "拍摄时间",
+	KeyScrubLabel:            "去掉位置和设备等隐私元数据",
+	KeyScrubHint:             "去掉 GPS 位置、设备型号、编码器和用户备注等标签, 保留拍摄时间",
+	KeyScrubbedTags:          "已去掉的元数据:",
+	KeyTusNotFound:           "上传 %s 不存在或尚未完成, 请重新上传。",
+	KeyUploadResumable:       "上传中断, 再次提交即可从中断处继续上传。",
+	KeyLibraryTitle:          "输出文件库",
+	KeyLibraryLink:           "浏览输出文件库",
+	KeyLibrarySearch:         "搜索文件名",
+	KeyLibrarySortNewest:     "最新优先",
+	KeyLibrarySortOldest:     "最早优先",
+	KeyLibrarySortName:       "文件名",
+	KeyLibrarySortSize:       "最大优先",
+	KeyLibrarySortDuration:   "最长优先",
+	KeyLibraryEmpty:          "输出目录中没有文件",
+	KeyLibraryPrev:           "上一页",
+	KeyLibraryNext:           "下一页",
+	KeyLibraryRename:         "重命名",
+	KeyLibraryRenamePrompt:   "新文件名",
+	KeyLibraryInvalidName:    "文件名无效",
+	KeyLibraryFileExists:     "已存在同名文件",
+	KeyLibraryExtMismatch:    "新文件名必须保留 %s 扩展名",
+	KeyInsufficientStorage:   "服务器磁盘空间不足: 需要 %s, 可用 %s",
+	KeyMaxUploadHint:         "单个文件最大: %s",
+	KeyDiskUsageHint:         "服务器磁盘: 可用 %s / 共 %s (已用 %d%%)",
+	KeyAdminRequired:         "需要管理员令牌",
+	KeyAuthRequired:          "需要登录或有效的访问令牌",
+	KeyLoginTitle:            "登录",
+	KeyLoginPassword:         "密码",
+	KeyLoginButton:           "登录",
+	KeyLoginFailed:           "密码错误",
+	KeyLoginLocked:           "失败次数过多, 请 %d 分钟后再试",
+	KeyLoginDisabled:         "未启用密码登录, 请使用 API 令牌(Authorization: Bearer <令牌>)",
+	KeyLogout:                "退出登录",
+	KeyPairLink:              "手机配对",
+	KeyPairTitle:             "手机扫码配对",
+	KeyPairHint:              "用手机相机扫码即可打开本页面, 无需输入地址和密码。二维码只能使用一次, %d 分钟后失效。",
+	KeyPairInvalid:           "配对二维码无效、已使用或已过期, 请重新生成。",
+	KeyDevicesTitle:          "已配对设备",
+	KeyDevicesEmpty:          "暂无已配对的设备。",
+	KeyDevicePairedAt:        "配对于",
+	KeyDeviceLastSeen:        "最近访问",
+	KeyDeviceRevoke:          "撤销",
+	KeyDeviceConfirmRevoke:   "确认撤销该设备吗？撤销后需要重新登录或配对。",
+	KeyAdminTokenPrompt:      "管理员令牌",
+	KeyAdminTokenInvalid:     "管理员令牌错误",
+	KeyAdminDisabled:         "未启用管理功能, 请在 config.yaml 中设置 admin_token。",
+	KeyStagedInUse:           "暂存文件 %s 正在被其他请求提交。",
+	KeyNoIntroMatch:          "未匹配到参考片头, 已使用固定的掐头时长",
+	KeyNoOutroMatch:          "未匹配到参考片尾, 已使用固定的去尾时长",
 }
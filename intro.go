@@ -0,0 +1,113 @@
+//
+// FilePath    : video-trim\intro.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 自动检测同一批上传视频中共同的片头
+//
+
+package main
+
+import (
+	"log"
+	"os/exec"
+	"time"
+)
+
+// 掐头方式
+const (
+	trimModeFixed = "fixed" // 使用固定的掐头时长
+	trimModeIntro = "intro" // 自动检测批次中共同的片头
+)
+
+// detectJobIntros 对任务中的每个文件抽取开头若干秒的帧哈希, 找出与批次中其他文件共同的开头时长
+// 检测结果写入 JobFile.DetectedHead; 未检测到或短于固定的掐头时长时保持为 0, 使用固定的掐头时长
+func detectJobIntros(job *Job) {
+	if len(job.Files) < 2 {
+		log.Printf("job %s: intro detection needs at least 2 files", job.ID)
+		return
+	}
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		log.Printf("job %s: intro detection skipped, ffmpeg not found in PATH: %v", job.ID, err)
+		return
+	}
+
+	window := time.Duration(introDetectSeconds) * time.Second
+	hashes := make([][]uint64, len(job.Files))
+
+	for i, f := range job.Files {
+		if job.ctx.Err() != nil {
+			return
+		}
+
+		h, err := sampleFrameHashes(job.ctx, ffmpegPath, f.inputPath, 0, window, hashSampleFPS)
+		if err != nil {
+			log.Printf("job %s: intro detection for %s error: %v", job.ID, f.Name, err)
+			continue
+		}
+
+		hashes[i] = h
+	}
+
+	heads := commonIntroLengths(hashes, hashSampleFPS)
+
+	job.update(func(j *Job) {
+		for i, f := range j.Files {
+			// 检测到的片头比固定的掐头时长还短时, 多半只是共同的开场画面, 不能代替固定时长
+			if heads[i] > j.Options.Head {
+				f.DetectedHead = heads[i].Seconds()
+			}
+		}
+	})
+}
+
+// commonIntroLengths 计算每个文件与批次中任一其他文件开头连续相同画面的最长时长
+// 相同开头中不是纯色的画面少于 1 秒时视为巧合(如都从黑屏淡入), 返回 0
+func commonIntroLengths(hashes [][]uint64, fps int) []time.Duration {
+	heads := make([]time.Duration, len(hashes))
+
+	for i := range hashes {
+		best := 0
+
+		for j := range hashes {
+			if i == j {
+				continue
+			}
+
+			if n := sharedPrefixFrames(hashes[i], hashes[j]); informativeFrames(hashes[i][:n]) >= fps {
+				best = max(best, n)
+			}
+		}
+
+		if best >= fps {
+			heads[i] = framesToDuration(best, fps)
+		}
+	}
+
+	return heads
+}
+
+// sharedPrefixFrames 返回两个帧哈希序列从头开始连续相似的帧数
+func sharedPrefixFrames(a, b []uint64) int {
+	n := 0
+	for n < len(a) && n < len(b) && framesMatch(a[n], b[n]) {
+		n++
+	}
+
+	return n
+}
+
+// informativeFrames 返回帧哈希序列中不是纯色画面的帧数
+func informativeFrames(hashes []uint64) int {
+	n := 0
+
+	for _, h := range hashes {
+		if !lowInformationHash(h) {
+			n++
+		}
+	}
+
+	return n
+}
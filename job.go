@@ -0,0 +1,585 @@
+//
+// FilePath    : video-trim\job.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 异步裁剪任务队列与工作池
+//
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// JobState 任务(或任务中单个文件)的状态
+type JobState string
+
+// 任务状态常量
+const (
+	JobQueued   JobState = "queued"   // 排队中
+	JobRunning  JobState = "running"  // 处理中
+	JobDone     JobState = "done"     // 已完成
+	JobFailed   JobState = "failed"   // 失败
+	JobCanceled JobState = "canceled" // 已取消
+)
+
+// errQueueFull 任务队列已满
+var errQueueFull = errors.New("job queue is full")
+
+// JobFile 任务中的单个文件及其处理结果
+type JobFile struct {
+	Name   string   `json:"name"`             // 上传时的原始文件名
+	State  JobState `json:"state"`            // 文件处理状态
+	Output string   `json:"output,omitempty"` // 输出文件名
+	Link   string   `json:"link,omitempty"`   // 下载链接
+	Error  string   `json:"error,omitempty"`  // 失败原因
+
+	Progress float64 `json:"progress"`        // 处理进度百分比(0-100)
+	Speed    float64 `json:"speed,omitempty"` // ffmpeg 处理速度倍率
+	ETA      float64 `json:"eta,omitempty"`   // 预计剩余时间(秒)
+
+	DetectedHead float64 `json:"detected_head,omitempty"`  // 自动检测到的片头时长(秒)
+	DetectedTail float64 `json:"detected_tail,omitempty"`  // 匹配到的片尾时长(秒)
+	MatchedIntro string  `json:"matched_intro,omitempty"`  // 匹配到的片头参考片段名称
+	MatchedOutro string  `json:"matched_outro,omitempty"`  // 匹配到的片尾参考片段名称
+	NoIntroMatch bool    `json:"no_intro_match,omitempty"` // 匹配参考片段时未找到片头, 使用固定的掐头时长
+	NoOutroMatch bool    `json:"no_outro_match,omitempty"` // 匹配参考片段时未找到片尾, 使用固定的去尾时长
+
+	Requested []timeRange `json:"requested,omitempty"` // 按参数计算的保留片段
+	Segments  []timeRange `json:"segments,omitempty"`  // 对齐关键帧后实际导出的片段
+	Warnings  []string    `json:"warnings,omitempty"`  // 流被转换或丢弃等提示
+	Scrubbed  []string    `json:"scrubbed,omitempty"`  // 隐私清理去掉的元数据标签
+
+	Cover float64 `json:"cover,omitempty"` // 封面帧在输出视频中的时间(秒)
+
+	Head *float64 `json:"head,omitempty"` // 在时间轴上选择的掐头时长(秒), 优先于任务参数和检测结果
+	Tail *float64 `json:"tail,omitempty"` // 在时间轴上选择的去尾时长(秒)
+
+	CapturedAt *time.Time `json:"captured_at,omitempty"` // 拍摄时间, 同时作为输出文件的修改时间
+
+	inputPath string    // 已保存到 uploadDir 的临时输入文件
+	modTime   time.Time // 浏览器提供的文件修改时间
+}
+
+// Job 一次上传对应的裁剪任务
+type Job struct {
+	ID        string      `json:"id"`         // 任务 ID
+	State     JobState    `json:"state"`      // 任务状态
+	Options   trimOptions `json:"options"`    // 裁剪参数
+	Files     []*JobFile  `json:"files"`      // 文件列表
+	CreatedAt time.Time   `json:"created_at"` // 创建时间
+	UpdatedAt time.Time   `json:"updated_at"` // 最近更新时间
+
+	owner string // 创建任务的会话 ID
+
+	mu      sync.RWMutex
+	changed chan struct{} // 每次更新时关闭并替换, 用于通知订阅者
+
+	ctx    context.Context    // 任务上下文, 取消后结束正在运行的 ffmpeg/ffprobe
+	cancel context.CancelFunc // 取消任务上下文
+}
+
+// Snapshot 返回任务的只读副本, 可安全地序列化或渲染
+func (j *Job) Snapshot() *Job {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	files := make([]*JobFile, len(j.Files))
+	for i, f := range j.Files {
+		cp := *f
+		files[i] = &cp
+	}
+
+	return &Job{
+		ID:        j.ID,
+		State:     j.State,
+		Options:   j.Options,
+		Files:     files,
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+	}
+}
+
+// update 在锁保护下修改任务, 刷新更新时间并通知订阅者
+func (j *Job) update(fn func(j *Job)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	fn(j)
+	j.UpdatedAt = time.Now()
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// Changed 返回一个在任务下次更新时关闭的通道
+func (j *Job) Changed() <-chan struct{} {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.changed
+}
+
+// Finished 判断任务是否已结束(完成、失败或取消)
+func (j *Job) Finished() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.State.finished()
+}
+
+// finished 判断状态是否为结束状态
+func (s JobState) finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCanceled
+}
+
+// Cancel 取消任务: 结束正在运行的 ffmpeg 进程, 并删除尚未处理的临时输入文件
+// 任务已结束时返回 false
+func (j *Job) Cancel() bool {
+	canceled := false
+
+	j.update(func(j *Job) {
+		if j.State.finished() {
+			return
+		}
+
+		canceled = true
+
+		j.cancel()
+
+		for _, f := range j.Files {
+			if f.State == JobQueued {
+				f.State = JobCanceled
+				os.Remove(f.inputPath)
+			}
+		}
+
+		// 尚未开始的任务直接结束, 运行中的任务由 worker 在 ffmpeg 退出后收尾
+		if j.State == JobQueued {
+			j.State = JobCanceled
+		}
+	})
+
+	return canceled
+}
+
+// jobQueue 有界任务队列, 由固定数量的 worker 消费
+type jobQueue struct {
+	mu    sync.RWMutex
+	jobs  map[string]*Job
+	ch    chan *Job
+	slots chan struct{} // 已预留的队列位置, 容量与 ch 相同, worker 取出任务后释放
+}
+
+// jobs 全局任务队列, 在 main 中通过 startJobQueue 初始化
+var jobs *jobQueue
+
+// startJobQueue 创建任务队列并启动 workers 个处理协程
+func startJobQueue(workers, size int) *jobQueue {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	if size <= 0 {
+		size = 1
+	}
+
+	q := &jobQueue{
+		jobs:  map[string]*Job{},
+		ch:    make(chan *Job, size),
+		slots: make(chan struct{}, size),
+	}
+
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+
+	return q
+}
+
+// newJob 创建一个处于排队状态的任务, id 由调用方通过 newJobID 生成(上传文件按任务 ID 命名)
+func newJob(id string, opts trimOptions) *Job {
+	now := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Job{
+		ID:        id,
+		State:     JobQueued,
+		Options:   opts,
+		Files:     []*JobFile{},
+		CreatedAt: now,
+		UpdatedAt: now,
+		changed:   make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// newJobID 生成随机的任务 ID
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand 理论上不会失败, 退化为时间戳保证可用
+		return hex.EncodeToString([]byte(time.Now().Format("150405.000000")))
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Reserve 预留队列中的一个位置, 队列已满时立即返回 errQueueFull
+// 预留成功后必须调用 Submit 或 Release; 先预留再移动输入文件, 队列已满时不会丢失用户已上传的文件
+func (q *jobQueue) Reserve() error {
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// Release 释放未使用的预留位置
+func (q *jobQueue) Release() {
+	<-q.slots
+}
+
+// Submit 将任务放入已预留的位置, 预留的位置保证队列有空间, 因此不会阻塞
+func (q *jobQueue) Submit(job *Job) {
+	q.mu.Lock()
+	q.jobs[job.ID] = job
+	q.mu.Unlock()
+
+	q.ch <- job
+}
+
+// Get 根据 ID 查找任务
+func (q *jobQueue) Get(id string) (*Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	job, ok := q.jobs[id]
+
+	return job, ok
+}
+
+// CancelAll 取消全部未结束的任务, 返回取消的数量
+func (q *jobQueue) CancelAll() int {
+	q.mu.RLock()
+	all := make([]*Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		all = append(all, j)
+	}
+	q.mu.RUnlock()
+
+	n := 0
+
+	for _, j := range all {
+		if j.Cancel() {
+			n++
+		}
+	}
+
+	return n
+}
+
+// EvictFinished 移除在 before 之前结束的任务, 释放其文件列表和进度信息, 返回移除的数量
+func (q *jobQueue) EvictFinished(before time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := 0
+
+	for id, j := range q.jobs {
+		j.mu.RLock()
+		expired := j.State.finished() && j.UpdatedAt.Before(before)
+		j.mu.RUnlock()
+
+		if expired {
+			delete(q.jobs, id)
+			n++
+		}
+	}
+
+	return n
+}
+
+// renameOutput 在文件库中重命名或删除输出文件后同步任务中的输出文件名和下载链接, newName 为空表示已删除
+func (q *jobQueue) renameOutput(oldName, newName string) {
+	q.mu.RLock()
+	all := make([]*Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		all = append(all, j)
+	}
+	q.mu.RUnlock()
+
+	for _, j := range all {
+		if !j.hasOutput(oldName) {
+			continue
+		}
+
+		j.update(func(j *Job) {
+			for _, f := range j.Files {
+				if f.Output != oldName {
+					continue
+				}
+
+				f.Output = newName
+				f.Link = ""
+
+				if newName != "" {
+					f.Link = "/download/" + newName
+				}
+			}
+		})
+	}
+}
+
+// hasOutput 判断任务中是否有文件的输出为 name
+func (j *Job) hasOutput(name string) bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	for _, f := range j.Files {
+		if f.Output == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// worker 持续从队列中取出任务并处理
+func (q *jobQueue) worker() {
+	for job := range q.ch {
+		q.Release()
+		runJob(job)
+	}
+}
+
+// runJob 逐个处理任务中的文件并更新状态
+func runJob(job *Job) {
+	defer job.cancel()
+
+	// 任务结束(包括失败和取消)后删除整个工作目录, 已完成的输出此前已移入输出目录
+	defer removeJobWorkspace(job.ID)
+
+	// 排队期间已被取消的任务无需处理
+	if job.Finished() {
+		return
+	}
+
+	workDir := jobWorkDir(job.ID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		log.Printf("job %s: create workspace error: %v", job.ID, err)
+		failJob(job, err)
+
+		return
+	}
+
+	job.update(func(j *Job) { j.State = JobRunning })
+
+	// 同一任务中已自动选择的封面, 使各文件的封面尽量不同
+	var covers []uint64
+
+	// 自动检测片头或匹配参考片段, 作为各文件的掐头/去尾时长
+	switch job.Options.TrimMode {
+	case trimModeIntro:
+		detectJobIntros(job)
+	case trimModeReference:
+		matchJobReferences(job)
+	}
+
+	for _, f := range job.Files {
+		if job.ctx.Err() != nil {
+			break
+		}
+
+		job.update(func(*Job) { f.State = JobRunning })
+
+		onProgress := func(p ffmpegProgress) {
+			job.update(func(*Job) {
+				f.Progress = p.Percent
+				f.Speed = p.Speed
+				f.ETA = p.ETA
+			})
+		}
+
+		var captured time.Time
+
+		onPlan := func(plan cutPlan) {
+			captured = plan.CapturedAt
+
+			job.update(func(*Job) {
+				f.Requested = plan.Requested
+				f.Segments = plan.Segments
+				f.Warnings = plan.Warnings
+				f.Scrubbed = plan.Scrubbed
+
+				if !plan.CapturedAt.IsZero() {
+					f.CapturedAt = &plan.CapturedAt
+				}
+			})
+		}
+
+		outPath, err := processFileWithTimeout(job.ctx, f, workDir, fileTrimOptions(job.Options, f), onProgress, onPlan)
+		if err != nil {
+			// 任务被取消导致的失败标记为已取消
+			state := JobFailed
+			if job.ctx.Err() != nil {
+				state = JobCanceled
+			} else {
+				log.Printf("job %s: process file %s error: %v", job.ID, f.Name, err)
+			}
+
+			job.update(func(*Job) {
+				f.State = state
+				f.Error = err.Error()
+			})
+
+			continue
+		}
+
+		// 设置封面帧, 失败只记录为警告, 不影响裁剪结果
+		if job.Options.Cover != coverNone {
+			at, hash, err := embedCover(job.ctx, outPath, job.Options, covers)
+			if err == nil && job.Options.Cover == coverAuto {
+				covers = append(covers, hash)
+			}
+
+			job.update(func(*Job) {
+				if err != nil {
+					f.Warnings = append(f.Warnings, "cover not set: "+err.Error())
+				} else {
+					f.Cover = at.Seconds()
+				}
+			})
+		}
+
+		// 封面会重新封装输出, 因此最后再设置修改时间
+		setCaptureTime(outPath, captured)
+
+		// 处理期间任务已被取消(如管理员清理全部文件)时不再发布
+		if job.ctx.Err() != nil {
+			job.update(func(*Job) {
+				f.State = JobCanceled
+				f.Error = job.ctx.Err().Error()
+			})
+
+			continue
+		}
+
+		// 全部处理完成后才移入输出目录, 文件库和下载不会看到未完成的文件
+		outName, err := publishOutput(outPath, job.owner)
+		if err != nil {
+			log.Printf("job %s: publish %s error: %v", job.ID, f.Name, err)
+			os.Remove(outPath)
+
+			job.update(func(*Job) {
+				f.State = JobFailed
+				f.Error = err.Error()
+			})
+
+			continue
+		}
+
+		job.update(func(*Job) {
+			f.State = JobDone
+			f.Progress = 100
+			f.ETA = 0
+			f.Output = outName
+			f.Link = "/download/" + outName
+		})
+	}
+
+	job.update(func(j *Job) {
+		j.State = finalJobState(j)
+	})
+}
+
+// fileTrimOptions 返回单个文件实际使用的裁剪参数: 检测到片头/片尾时以其代替固定的掐头/去尾时长
+// 在时间轴上手动选择的入点/出点优先级最高
+func fileTrimOptions(opts trimOptions, f *JobFile) trimOptions {
+	if f.DetectedHead > 0 {
+		opts.Head = time.Duration(f.DetectedHead * float64(time.Second))
+	}
+
+	if f.DetectedTail > 0 {
+		opts.Tail = time.Duration(f.DetectedTail * float64(time.Second))
+	}
+
+	if f.Head != nil {
+		opts.Head = time.Duration(*f.Head * float64(time.Second))
+	}
+
+	if f.Tail != nil {
+		opts.Tail = time.Duration(*f.Tail * float64(time.Second))
+	}
+
+	opts.ModTime = f.modTime
+
+	return opts
+}
+
+// processFileWithTimeout 在单文件最长处理时间限制下处理文件
+func processFileWithTimeout(ctx context.Context, f *JobFile, workDir string, opts trimOptions, onProgress progressFunc, onPlan planFunc) (string, error) {
+	if maxProcessSeconds > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(maxProcessSeconds)*time.Second)
+		defer cancel()
+	}
+
+	outPath, err := processSingleFile(ctx, f.inputPath, workDir, f.Name, opts, onProgress, onPlan)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("processing exceeded %d seconds: %w", maxProcessSeconds, err)
+	}
+
+	return outPath, err
+}
+
+// finalJobState 根据文件处理结果计算任务的最终状态
+func finalJobState(j *Job) JobState {
+	if j.ctx.Err() != nil {
+		return JobCanceled
+	}
+
+	// 只要有文件成功即视为完成, 全部失败才标记为失败
+	for _, f := range j.Files {
+		if f.State == JobDone {
+			return JobDone
+		}
+	}
+
+	return JobFailed
+}
+
+// failJob 任务无法开始处理时将全部文件标记为失败, 并删除临时输入文件
+func failJob(job *Job, err error) {
+	removeJobInputs(job)
+
+	job.update(func(j *Job) {
+		for _, f := range j.Files {
+			f.State = JobFailed
+			f.Error = err.Error()
+		}
+
+		j.State = JobFailed
+	})
+}
+
+// removeJobInputs 删除任务中尚未处理的临时输入文件
+func removeJobInputs(job *Job) {
+	for _, f := range job.Files {
+		if f.inputPath != "" {
+			os.Remove(f.inputPath)
+		}
+	}
+}
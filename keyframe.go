@@ -0,0 +1,196 @@
+//
+// FilePath    : video-trim\keyframe.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 关键帧对齐: 流复制只能从关键帧开始, 将片段起点对齐到关键帧并报告实际剪切位置
+//
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 片段起点对齐关键帧的方式
+const (
+	snapBack    = "back"    // 对齐到之前的关键帧, 多保留一点内容(与 ffmpeg 流复制的默认行为一致)
+	snapForward = "forward" // 对齐到之后的关键帧, 多去掉一点内容
+	snapNearest = "nearest" // 对齐到最近的关键帧
+)
+
+// cutPlan 单个文件的导出计划
+type cutPlan struct {
+	Requested []timeRange // 按参数计算的片段
+	Segments  []timeRange // 对齐关键帧后实际导出的片段
+	Warnings  []string    // 流转换或丢弃等提示
+	Scrubbed  []string    // 隐私清理去掉的元数据标签
+
+	CapturedAt time.Time // 拍摄时间, 未知时为零值
+}
+
+// planFunc 导出计划回调, 在开始导出前调用
+type planFunc func(plan cutPlan)
+
+// validSnapMode 判断是否为支持的对齐方式
+func validSnapMode(mode string) bool {
+	return mode == snapBack || mode == snapForward || mode == snapNearest || mode == snapPrecise
+}
+
+// probeKeyframes 使用 ffprobe 列出第一条视频流的关键帧时间(相对于文件起始时间, 已排序)
+// 只读取数据包标记, 不解码画面
+func probeKeyframes(ctx context.Context, absInput string) ([]time.Duration, error) {
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	// csv=p=1 会在每行前输出所属部分(packet/format), 便于区分
+	cmd := newCommand(ctx, ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "packet=pts_time,flags:format=start_time",
+		"-of", "csv=p=1",
+		absInput,
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("probe keyframes: %w", err)
+	}
+
+	return parseKeyframes(out), nil
+}
+
+// parseKeyframes 解析 ffprobe 输出的数据包列表, 只保留带 K 标记的关键帧
+// -ss 的时间相对于容器起始时间, 因此关键帧时间需要减去 format 的 start_time
+func parseKeyframes(out []byte) []time.Duration {
+	var (
+		pts   []float64
+		start float64
+	)
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+
+	for sc.Scan() {
+		fields := strings.Split(strings.TrimSpace(sc.Text()), ",")
+
+		switch {
+		case len(fields) == 3 && fields[0] == "packet" && strings.HasPrefix(fields[2], "K"):
+			if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				pts = append(pts, v)
+			}
+		case len(fields) == 2 && fields[0] == "format":
+			if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				start = v
+			}
+		}
+	}
+
+	keyframes := make([]time.Duration, 0, len(pts))
+	for _, v := range pts {
+		keyframes = append(keyframes, max(time.Duration(math.Round((v-start)*float64(time.Second))), 0))
+	}
+
+	sort.Slice(keyframes, func(i, j int) bool { return keyframes[i] < keyframes[j] })
+
+	return keyframes
+}
+
+// snapTime 按 mode 将时间对齐到关键帧, keyframes 必须已排序
+func snapTime(t time.Duration, keyframes []time.Duration, mode string) time.Duration {
+	// i 为第一个大于 t 的关键帧
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i] > t })
+
+	prev := time.Duration(0)
+	if i > 0 {
+		prev = keyframes[i-1]
+	}
+
+	// 已经在关键帧上或之后没有关键帧时, 只能向前对齐
+	if prev == t || i == len(keyframes) {
+		return prev
+	}
+
+	next := keyframes[i]
+
+	switch mode {
+	case snapForward:
+		return next
+	case snapNearest:
+		if next-t < t-prev {
+			return next
+		}
+
+		return prev
+	default:
+		return prev
+	}
+}
+
+// snapSegments 将各片段的起点对齐到关键帧, 去掉对齐后过短的片段并合并重叠的片段
+func snapSegments(segments []timeRange, keyframes []time.Duration, mode string) []timeRange {
+	snapped := []timeRange{}
+
+	for _, s := range segments {
+		if s.Start > 0 {
+			s.Start = snapTime(s.Start, keyframes, mode)
+		}
+
+		if s.End > 0 && s.Len() < minSegment {
+			continue
+		}
+
+		snapped = append(snapped, s)
+	}
+
+	// 向前对齐可能使片段与上一个片段重叠, 合并后内容与分别导出一致且不会重复
+	if len(snapped) > 1 {
+		return mergeRanges(snapped)
+	}
+
+	return snapped
+}
+
+// planPieces 按对齐方式生成导出片段, 同时返回实际导出的片段范围
+// 精确模式无法重新编码(如编码不支持)时退回到对齐之前的关键帧
+func planPieces(ctx context.Context, absInput string, segments []timeRange, keyframes []time.Duration, mode string) ([]renderPiece, []timeRange, error) {
+	if mode == snapPrecise {
+		pieces, err := planPrecisePieces(ctx, absInput, segments, keyframes)
+		if err == nil {
+			return pieces, segments, nil
+		}
+
+		log.Printf("precise cut unavailable for %s, snapping to previous keyframe: %v", absInput, err)
+
+		mode = snapBack
+	}
+
+	snapped := snapSegments(segments, keyframes, mode)
+	if len(snapped) == 0 {
+		return nil, nil, fmt.Errorf("nothing left to keep after snapping to keyframes")
+	}
+
+	return copyPieces(snapped), snapped, nil
+}
+
+// needsKeyframes 判断片段是否需要对齐关键帧(只有从 0 开始的单个片段不需要)
+func needsKeyframes(segments []timeRange) bool {
+	for _, s := range segments {
+		if s.Start > 0 {
+			return true
+		}
+	}
+
+	return false
+}
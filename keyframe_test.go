@@ -0,0 +1,126 @@
+//
+// FilePath    : video-trim\keyframe_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 关键帧解析和对齐的测试
+//
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseKeyframes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []time.Duration
+	}{
+		{name: "empty", in: "", want: []time.Duration{}},
+		{
+			name: "only key packets",
+			in:   "packet,0.000000,K_\npacket,0.033000,__\npacket,2.002000,K__\npacket,4.004000,K_\n",
+			want: []time.Duration{0, secs(2.002), secs(4.004)},
+		},
+		{
+			name: "relative to start time and sorted",
+			in:   "packet,3.500000,K_\npacket,1.500000,K_\npacket,2.500000,K_\nformat,1.500000\n",
+			want: []time.Duration{0, secs(1), secs(2)},
+		},
+		{
+			name: "before start time clamped",
+			in:   "packet,1.000000,K_\npacket,2.000000,K_\nformat,1.500000\n",
+			want: []time.Duration{0, secs(0.5)},
+		},
+		{
+			name: "malformed lines ignored",
+			in:   "packet,N/A,K_\nstream,1\n\npacket,1.0,K_\n",
+			want: []time.Duration{secs(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := parseKeyframes([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseKeyframes() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnapTime(t *testing.T) {
+	keyframes := []time.Duration{0, secs(2), secs(4), secs(6)}
+
+	tests := []struct {
+		at        time.Duration
+		mode      string
+		keyframes []time.Duration
+		want      time.Duration
+	}{
+		{at: secs(3), mode: snapBack, want: secs(2)},
+		{at: secs(3), mode: snapForward, want: secs(4)},
+		{at: secs(3), mode: snapNearest, want: secs(2)},
+		{at: secs(3.5), mode: snapNearest, want: secs(4)},
+		{at: secs(2.5), mode: snapNearest, want: secs(2)},
+		{at: secs(4), mode: snapBack, want: secs(4)},
+		{at: secs(4), mode: snapForward, want: secs(4)},
+		{at: secs(7), mode: snapForward, want: secs(6)},
+		{at: secs(1), mode: snapPrecise, want: 0},
+		{at: secs(3), mode: snapForward, keyframes: []time.Duration{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		kf := keyframes
+		if tt.keyframes != nil {
+			kf = tt.keyframes
+		}
+
+		if got := snapTime(tt.at, kf, tt.mode); got != tt.want {
+			t.Errorf("snapTime(%v, %s) = %v, want %v", tt.at, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestSnapSegments(t *testing.T) {
+	keyframes := []time.Duration{0, secs(2), secs(4), secs(6)}
+
+	tests := []struct {
+		name     string
+		segments []timeRange
+		mode     string
+		want     []timeRange
+	}{
+		{
+			name:     "back snap merges overlapping segments",
+			segments: []timeRange{{0, secs(3)}, {secs(3.5), secs(10)}},
+			mode:     snapBack,
+			want:     []timeRange{{0, secs(10)}},
+		},
+		{
+			name:     "forward snap keeps segments apart",
+			segments: []timeRange{{0, secs(3)}, {secs(3.5), secs(10)}},
+			mode:     snapForward,
+			want:     []timeRange{{0, secs(3)}, {secs(4), secs(10)}},
+		},
+		{
+			name:     "segment too short after snapping is dropped",
+			segments: []timeRange{{secs(1), secs(2.5)}, {secs(4.5), secs(6.005)}},
+			mode:     snapForward,
+			want:     []timeRange{{secs(2), secs(2.5)}},
+		},
+		{
+			name:     "open segment",
+			segments: []timeRange{{Start: secs(3)}},
+			mode:     snapBack,
+			want:     []timeRange{{Start: secs(2)}},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := snapSegments(tt.segments, keyframes, tt.mode); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: snapSegments() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,514 @@
+//
+// FilePath    : video-trim\library.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 输出文件库: 浏览、搜索、排序输出目录中的文件, 并支持单个文件的删除和重命名
+//
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文件库每页数量
+const (
+	libraryPerPage    = 24
+	libraryMaxPerPage = 100
+)
+
+// libraryItem 输出目录中的一个文件
+type libraryItem struct {
+	Name      string    `json:"name"`       // 文件名
+	Size      int64     `json:"size"`       // 文件大小(字节)
+	Duration  float64   `json:"duration"`   // 媒体时长(秒), 未知时为 0
+	CreatedAt time.Time `json:"created_at"` // 文件修改时间, 保留元数据时即拍摄时间
+	Link      string    `json:"link"`       // 下载链接
+	Thumbnail string    `json:"thumbnail"`  // 缩略图链接
+
+	path    string      // 文件路径
+	info    os.FileInfo // 文件信息, 用于缓存时长和缩略图
+	durDone bool        // 是否已读取时长
+}
+
+// libraryPage 文件库的一页列表
+type libraryPage struct {
+	Items   []*libraryItem `json:"items"`    // 当前页的文件
+	Total   int            `json:"total"`    // 符合搜索条件的文件总数
+	Page    int            `json:"page"`     // 当前页码(从 1 开始)
+	PerPage int            `json:"per_page"` // 每页数量
+}
+
+// libraryQuery 列表的搜索、排序和分页参数
+type libraryQuery struct {
+	Search  string // 文件名关键字(不区分大小写)
+	Sort    string // 排序字段: created、name、size、duration
+	Desc    bool   // 是否降序
+	Page    int    // 页码(从 1 开始)
+	PerPage int    // 每页数量
+	Owner   string // 只列出对该会话可见的文件, 为空时列出全部(管理员)
+}
+
+// parseLibraryQuery 解析查询参数, 无效值使用默认值: 按时间从新到旧, 第 1 页
+func parseLibraryQuery(r *http.Request) libraryQuery {
+	v := r.URL.Query()
+
+	q := libraryQuery{
+		Search:  strings.TrimSpace(v.Get("q")),
+		Sort:    v.Get("sort"),
+		Page:    1,
+		PerPage: libraryPerPage,
+	}
+
+	switch q.Sort {
+	case "name", "size", "duration", "created":
+	default:
+		q.Sort = "created"
+	}
+
+	// 名称默认升序, 其他字段默认降序(最新、最大、最长在前)
+	q.Desc = q.Sort != "name"
+
+	switch v.Get("order") {
+	case "asc":
+		q.Desc = false
+	case "desc":
+		q.Desc = true
+	}
+
+	if n, err := strconv.Atoi(v.Get("page")); err == nil && n > 0 {
+		q.Page = n
+	}
+
+	if n, err := strconv.Atoi(v.Get("per_page")); err == nil && n > 0 {
+		q.PerPage = min(n, libraryMaxPerPage)
+	}
+
+	return q
+}
+
+// libraryDurations 已读取的媒体时长, 文件大小或修改时间变化后重新读取
+var libraryDurations = struct {
+	sync.Mutex
+	m map[string]durationEntry
+}{m: map[string]durationEntry{}}
+
+// durationEntry 缓存的媒体时长
+type durationEntry struct {
+	Size     int64
+	ModTime  time.Time
+	Duration float64
+}
+
+// thumbnailMu 串行生成缩略图, 避免同时打开页面时启动大量 ffmpeg
+var thumbnailMu sync.Mutex
+
+// validLibraryName 判断文件名是否为输出目录下的普通文件名(不含路径、不是隐藏文件)
+func validLibraryName(name string) bool {
+	return name != "" && name == filepath.Base(name) && !strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, ".")
+}
+
+// resolveLibraryPath 校验文件名并返回其在输出目录下的绝对路径
+func resolveLibraryPath(name string) (string, error) {
+	if !validLibraryName(name) {
+		return "", fmt.Errorf("invalid file name")
+	}
+
+	return resolveWithinDir(filepath.Join(outputDir, name), outputDir)
+}
+
+// listLibrary 列出输出目录中的文件, 跳过子目录(包括处理中任务的工作目录)和隐藏文件
+func listLibrary() ([]*libraryItem, error) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*libraryItem, 0, len(entries))
+
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !validLibraryName(name) {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		escaped := url.PathEscape(name)
+
+		items = append(items, &libraryItem{
+			Name:      name,
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+			Link:      "/download/" + escaped,
+			Thumbnail: "/library/" + escaped + "/thumbnail.jpg",
+			path:      filepath.Join(outputDir, name),
+			info:      info,
+		})
+	}
+
+	return items, nil
+}
+
+// queryLibrary 按参数过滤、排序并分页, 只为需要的文件读取时长
+func queryLibrary(ctx context.Context, q libraryQuery) (*libraryPage, error) {
+	items, err := listLibrary()
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Search != "" || q.Owner != "" {
+		kw := strings.ToLower(q.Search)
+		filtered := items[:0]
+
+		for _, it := range items {
+			if strings.Contains(strings.ToLower(it.Name), kw) && (q.Owner == "" || visibleTo(outputOwners.Owner(it.Name), q.Owner)) {
+				filtered = append(filtered, it)
+			}
+		}
+
+		items = filtered
+	}
+
+	// 按时长排序时需要全部文件的时长
+	if q.Sort == "duration" {
+		for _, it := range items {
+			it.loadDuration(ctx)
+		}
+	}
+
+	compare := func(a, b *libraryItem) int {
+		switch q.Sort {
+		case "name":
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case "size":
+			return cmp.Compare(a.Size, b.Size)
+		case "duration":
+			return cmp.Compare(a.Duration, b.Duration)
+		default:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		c := compare(items[i], items[j])
+		if c == 0 {
+			// 相同时按文件名排列, 保证分页稳定
+			c = strings.Compare(items[i].Name, items[j].Name)
+			if q.Desc {
+				c = -c
+			}
+		}
+
+		if q.Desc {
+			return c > 0
+		}
+
+		return c < 0
+	})
+
+	page := &libraryPage{Total: len(items), Page: q.Page, PerPage: q.PerPage}
+
+	start := min((q.Page-1)*q.PerPage, len(items))
+	end := min(start+q.PerPage, len(items))
+	page.Items = items[start:end]
+
+	for _, it := range page.Items {
+		it.loadDuration(ctx)
+	}
+
+	return page, nil
+}
+
+// loadDuration 读取文件时长, 结果按文件大小和修改时间缓存; 读取失败时记为 0
+func (it *libraryItem) loadDuration(ctx context.Context) {
+	if it.durDone {
+		return
+	}
+
+	it.durDone = true
+
+	libraryDurations.Lock()
+	c, ok := libraryDurations.m[it.Name]
+	libraryDurations.Unlock()
+
+	if ok && c.Size == it.info.Size() && c.ModTime.Equal(it.info.ModTime()) {
+		it.Duration = c.Duration
+		return
+	}
+
+	if ffprobePath, err := exec.LookPath("ffprobe"); err == nil {
+		if d, err := getMediaDuration(ctx, ffprobePath, it.path); err == nil {
+			it.Duration = d.Seconds()
+		} else if ctx.Err() != nil {
+			// 请求被取消时不缓存结果
+			return
+		}
+	}
+
+	libraryDurations.Lock()
+	libraryDurations.m[it.Name] = durationEntry{Size: it.info.Size(), ModTime: it.info.ModTime(), Duration: it.Duration}
+	libraryDurations.Unlock()
+}
+
+// libraryThumbnailPath 返回缩略图路径, 以文件名、大小和修改时间区分, 文件变化后重新生成
+func libraryThumbnailPath(name string, info os.FileInfo) string {
+	sum := sha1.Sum(fmt.Appendf(nil, "%s|%d|%d", name, info.Size(), info.ModTime().UnixNano()))
+
+	return filepath.Join(uploadDir, "thumb_"+hex.EncodeToString(sum[:8])+".jpg")
+}
+
+// generateLibraryThumbnail 截取一帧作为缩略图, 位置为时长的 10%(最多 5 秒), 避开开头的黑屏
+func generateLibraryThumbnail(ctx context.Context, input, out string, duration float64) error {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	at := time.Duration(min(duration*0.1, 5) * float64(time.Second))
+	tmp := strings.TrimSuffix(out, ".jpg") + ".tmp.jpg"
+
+	cmd := newCommand(ctx, ffmpegPath,
+		"-nostdin", "-v", "error",
+		"-ss", formatFFmpegTime(at),
+		"-i", input,
+		"-map", "0:v:0",
+		"-an", "-sn", "-dn",
+		"-vf", fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", thumbWidth*2, thumbHeight*2),
+		"-frames:v", "1",
+		"-q:v", "5",
+		"-y", tmp,
+	)
+
+	if b, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("generate thumbnail: %w: %s", err, strings.TrimSpace(string(b)))
+	}
+
+	return os.Rename(tmp, out)
+}
+
+// removeLibraryCache 删除文件的缩略图和时长缓存
+func removeLibraryCache(name string, info os.FileInfo) {
+	os.Remove(libraryThumbnailPath(name, info))
+
+	libraryDurations.Lock()
+	delete(libraryDurations.m, name)
+	libraryDurations.Unlock()
+}
+
+// handleLibrary 文件库: 请求 JSON 时返回文件列表, 否则渲染页面(页面脚本再请求 JSON)
+func handleLibrary(w http.ResponseWriter, r *http.Request) {
+	if wantsJSON(r) {
+		q := parseLibraryQuery(r)
+
+		// 非管理员只能看到本会话生成的文件
+		if !isAdmin(r) {
+			q.Owner = requestSession(r)
+		}
+
+		page, err := queryLibrary(r.Context(), q)
+		if err != nil {
+			log.Printf("list library error: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	tmpl, err := template.New("template.html").Funcs(TemplateFuncMap).ParseFiles("template.html")
+	if err != nil {
+		log.Printf("parse template error: %v", err)
+		http.Error(w, "parse template error", http.StatusInternalServerError)
+
+		return
+	}
+
+	lang := detectLangFromRequest(r)
+
+	data := struct {
+		Lang    string
+		I18n    map[string]string
+		PerPage int
+	}{
+		Lang:    lang,
+		I18n:    getLocale(lang),
+		PerPage: libraryPerPage,
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "library", data); err != nil {
+		log.Printf("template execute error: %v", err)
+		http.Error(w, "template execute error", http.StatusInternalServerError)
+
+		return
+	}
+}
+
+// handleLibraryThumbnail 返回文件的缩略图, 首次请求时生成
+func handleLibraryThumbnail(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	path, err := resolveLibraryPath(name)
+	if err != nil || !canAccess(r, outputOwners.Owner(name)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	thumb := libraryThumbnailPath(name, info)
+
+	if _, err := os.Stat(thumb); err != nil {
+		it := &libraryItem{Name: name, path: path, info: info}
+		it.loadDuration(r.Context())
+
+		thumbnailMu.Lock()
+		// 等待期间可能已由其他请求生成
+		if _, err := os.Stat(thumb); err != nil {
+			err = generateLibraryThumbnail(r.Context(), path, thumb, it.Duration)
+			if err != nil {
+				thumbnailMu.Unlock()
+				log.Printf("thumbnail for %s error: %v", name, err)
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+				return
+			}
+		}
+		thumbnailMu.Unlock()
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeFile(w, r, thumb)
+}
+
+// handleLibraryDelete 删除输出目录中的单个文件
+func handleLibraryDelete(w http.ResponseWriter, r *http.Request) {
+	i18n := getLocale(detectLangFromRequest(r))
+	name := r.PathValue("name")
+
+	if !validLibraryName(name) {
+		http.Error(w, i18n[KeyLibraryInvalidName], http.StatusBadRequest)
+		return
+	}
+
+	if !canAccess(r, outputOwners.Owner(name)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	err := removeOutput(name)
+
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case os.IsNotExist(err):
+		http.NotFound(w, r)
+	default:
+		log.Printf("delete output %s error: %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleLibraryRename 重命名输出目录中的单个文件, 表单字段: name(新文件名)
+// 新文件名省略扩展名时沿用原扩展名; 不允许修改扩展名, 也不允许覆盖已有文件
+func handleLibraryRename(w http.ResponseWriter, r *http.Request) {
+	i18n := getLocale(detectLangFromRequest(r))
+	oldName := r.PathValue("name")
+
+	oldPath, err := resolveLibraryPath(oldName)
+	if err != nil {
+		http.Error(w, i18n[KeyLibraryInvalidName], http.StatusBadRequest)
+		return
+	}
+
+	if !canAccess(r, outputOwners.Owner(oldName)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	ext := filepath.Ext(oldName)
+
+	newName := strings.TrimSpace(r.FormValue("name"))
+	if newName != "" && filepath.Ext(newName) == "" {
+		newName += ext
+	}
+
+	newPath, err := resolveLibraryPath(newName)
+	if err != nil {
+		http.Error(w, i18n[KeyLibraryInvalidName], http.StatusBadRequest)
+		return
+	}
+
+	if !strings.EqualFold(filepath.Ext(newName), ext) {
+		http.Error(w, fmt.Sprintf(i18n[KeyLibraryExtMismatch], ext), http.StatusBadRequest)
+		return
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	info, err := os.Stat(oldPath)
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 不区分大小写的文件系统上只改大小写时 Lstat 会找到原文件本身, 此时允许重命名
+	if existing, err := os.Lstat(newPath); err == nil && !os.SameFile(info, existing) {
+		http.Error(w, i18n[KeyLibraryFileExists], http.StatusConflict)
+		return
+	}
+
+	if newName != oldName {
+		if err := os.Rename(oldPath, newPath); err != nil {
+			log.Printf("rename output %s error: %v", oldName, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		removeLibraryCache(oldName, info)
+		outputOwners.Rename(oldName, newName)
+		jobs.renameOutput(oldName, newName)
+	}
+
+	escaped := url.PathEscape(newName)
+
+	writeJSON(w, http.StatusOK, &libraryItem{
+		Name:      newName,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime(),
+		Link:      "/download/" + escaped,
+		Thumbnail: "/library/" + escaped + "/thumbnail.jpg",
+	})
+}
@@ -11,41 +11,123 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
 func main() {
+	// 生成写入 config.yaml 的密码或令牌哈希
+	if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+		runHashPassword()
+		return
+	}
+
 	// 读取配置文件
 	readConfig()
 
 	// 初始化目录
 	initDir()
 
+	// 初始化会话签名密钥并读取输出文件的归属
+	initSessions()
+
+	// 清理上次运行残留的临时文件
+	cleanOrphans()
+
 	// 初始化并加载本地化文件
 	EnsureLocaleExists()
 	loadLocales()
 
+	// 启动任务队列工作池
+	jobs = startJobQueue(workerCount, queueSize)
+
+	// 按保留时间和输出目录大小定期清理
+	startJanitor()
+
 	// 路由注册
 	http.HandleFunc("/", handleHome)
+	http.HandleFunc("GET /login", handleLoginPage)
+	http.HandleFunc("POST /login", handleLogin)
+	http.HandleFunc("POST /logout", handleLogout)
+	http.HandleFunc("GET /pair", handlePairPage)
+	http.HandleFunc("GET /pair/{token}", handlePairRedeem)
+	http.HandleFunc("GET /admin/devices", handleAdminDevices)
+	http.HandleFunc("POST /admin/login", handleAdminLogin)
+	http.HandleFunc("DELETE /admin/devices/{id}", handleAdminDeviceDelete)
 	http.HandleFunc("/upload", handleUpload)
 	http.HandleFunc("/download/", handleDownload)
-	http.HandleFunc("/clear", handleClear)
+	// 清理会删除文件, 拒绝其他站点页面发起的跨站请求
+	http.Handle("/clear", http.NewCrossOriginProtection().Handler(http.HandlerFunc(handleClear)))
+	http.HandleFunc("GET /jobs/{id}", handleJob)
+	http.HandleFunc("GET /jobs/{id}/events", handleJobEvents)
+	http.HandleFunc("GET /jobs/{id}/download.zip", handleJobZip)
+	http.HandleFunc("POST /jobs/{id}/cancel", handleJobCancel)
+	http.HandleFunc("GET /references", handleReferences)
+	http.HandleFunc("POST /references", handleReferenceUpload)
+	http.HandleFunc("DELETE /references/{name}", handleReferenceDelete)
+	http.HandleFunc("POST /stage", handleStage)
+	http.HandleFunc("GET /stage/{id}/sprite.jpg", handleStageSprite)
+	http.HandleFunc("GET /stage/{id}/thumbnails.vtt", handleStageThumbnails)
+	http.HandleFunc("DELETE /stage/{id}", handleStageDelete)
+	http.HandleFunc("GET /library", handleLibrary)
+	http.HandleFunc("GET /library/{name}/thumbnail.jpg", handleLibraryThumbnail)
+	http.HandleFunc("DELETE /library/{name}", handleLibraryDelete)
+	http.HandleFunc("PATCH /library/{name}", handleLibraryRename)
+	http.HandleFunc("OPTIONS /tus/{$}", handleTusOptions)
+	http.HandleFunc("POST /tus/{$}", handleTusCreate)
+	http.HandleFunc("OPTIONS /tus/{id}", handleTusOptions)
+	http.HandleFunc("HEAD /tus/{id}", handleTusHead)
+	http.HandleFunc("PATCH /tus/{id}", handleTusPatch)
+	http.HandleFunc("DELETE /tus/{id}", handleTusDelete)
+
+	// 确定监听地址, 配置了 bind_interface 时只监听该网卡的地址
+	addrs, err := listenAddresses()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	listeners := make([]net.Listener, 0, len(addrs))
 
-	// 打印本机局域网 IP, 方便访问
-	localIP := getLocalIP()
-	fmt.Printf("\n✅ Open in browser: http://%s%s\n\n", localIP, serverPort)
+	for _, addr := range addrs {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	// 通过 mDNS 广播服务, 并打印全部可用的访问地址, 方便访问
+	printAccessURLs(startMDNS())
 	fmt.Println("Ensure your browser and server are on the same LAN!")
+	fmt.Println()
+
+	// 打印手机扫码配对的二维码
+	printPairingQR()
 
 	// 启动 HTTP 服务并设置超时以避免资源耗尽 (读取超时, 写入超时, 空闲连接超时 从配置读取，单位: 秒)
 	srv := &http.Server{
 		Addr:         serverPort,
-		Handler:      http.DefaultServeMux,
+		Handler:      withSession(withAuth(http.DefaultServeMux)),
 		ReadTimeout:  time.Duration(readTimeoutSeconds) * time.Second,
 		WriteTimeout: time.Duration(writeTimeoutSeconds) * time.Second,
 		IdleTimeout:  time.Duration(idleTimeoutSeconds) * time.Second,
 	}
 
-	log.Printf("starting server on %s", serverPort)
-	log.Fatal(srv.ListenAndServe())
+	if authEnabled() {
+		log.Printf("authentication enabled (password: %t, tokens: %d)", authPasswordHash != "", len(authTokenHashes))
+	}
+
+	log.Printf("starting server on %s", strings.Join(addrs, ", "))
+
+	// 每个监听地址一个协程, 任一地址出错即退出
+	errc := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() { errc <- srv.Serve(l) }()
+	}
+
+	log.Fatal(<-errc)
 }
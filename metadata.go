@@ -0,0 +1,163 @@
+//
+// FilePath    : video-trim\metadata.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 元数据保留: 输出沿用原视频的拍摄时间、旋转角度等元数据, 并将文件修改时间设为拍摄时间
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appleCreationDateLayout iPhone 等设备写入的 com.apple.quicktime.creationdate 时间格式(带本地时区)
+const appleCreationDateLayout = "2006-01-02T15:04:05-0700"
+
+// mediaMetadata 需要在输出中保留的元数据
+type mediaMetadata struct {
+	CreationTime time.Time // 拍摄时间, 未知时为零值
+	Rotation     int       // 第一条视频流的显示旋转角度(逆时针, 度)
+}
+
+// probeMetadata 使用 ffprobe 读取拍摄时间和第一条视频流的旋转角度
+func probeMetadata(ctx context.Context, absInput string) (mediaMetadata, error) {
+	var md mediaMetadata
+
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return md, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	cmd := newCommand(ctx, ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "format_tags=creation_time,com.apple.quicktime.creationdate:stream_tags=rotate:stream_side_data=rotation",
+		"-of", "json",
+		absInput,
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return md, fmt.Errorf("probe metadata: %w", err)
+	}
+
+	return parseMetadata(out)
+}
+
+// parseMetadata 解析 ffprobe 输出的元数据
+// 优先使用带时区的 com.apple.quicktime.creationdate, 旋转角度优先使用 display matrix
+func parseMetadata(out []byte) (mediaMetadata, error) {
+	var (
+		md  mediaMetadata
+		res struct {
+			Format struct {
+				Tags map[string]string `json:"tags"`
+			} `json:"format"`
+			Streams []struct {
+				Tags struct {
+					Rotate string `json:"rotate"`
+				} `json:"tags"`
+				SideDataList []struct {
+					Rotation *float64 `json:"rotation"`
+				} `json:"side_data_list"`
+			} `json:"streams"`
+		}
+	)
+
+	if err := json.Unmarshal(out, &res); err != nil {
+		return md, fmt.Errorf("parse metadata: %w", err)
+	}
+
+	tags := res.Format.Tags
+
+	if t, err := time.Parse(appleCreationDateLayout, tags["com.apple.quicktime.creationdate"]); err == nil {
+		md.CreationTime = t
+	} else if t, err := time.Parse(time.RFC3339Nano, tags["creation_time"]); err == nil && !t.IsZero() {
+		md.CreationTime = t
+	}
+
+	if len(res.Streams) > 0 {
+		s := res.Streams[0]
+
+		// rotate 标签为顺时针角度, display matrix 为逆时针角度
+		if v, err := strconv.Atoi(s.Tags.Rotate); err == nil {
+			md.Rotation = -v
+		}
+
+		for _, sd := range s.SideDataList {
+			if sd.Rotation != nil {
+				md.Rotation = int(math.Round(*sd.Rotation))
+			}
+		}
+	}
+
+	return md, nil
+}
+
+// captureTime 返回拍摄时间, 元数据中没有时使用 fallback(如浏览器提供的文件修改时间)
+func (md mediaMetadata) captureTime(fallback time.Time) time.Time {
+	if !md.CreationTime.IsZero() {
+		return md.CreationTime
+	}
+
+	return fallback
+}
+
+// metadataArgs 构建保留全局元数据的输出参数, 元数据来源由调用方通过 -map_metadata 指定
+// MP4/MOV 默认只写入少数标准标签, use_metadata_tags 使拍摄时间、地点等自定义标签一并写入
+// 原视频没有 creation_time 时以 captured 写入, 使相册按拍摄时间排序
+func metadataArgs(ext string, md mediaMetadata, captured time.Time) []string {
+	var args []string
+
+	if isMP4Family(ext) {
+		args = append(args, "-movflags", "+use_metadata_tags")
+	}
+
+	if md.CreationTime.IsZero() && !captured.IsZero() {
+		args = append(args, "-metadata", "creation_time="+captured.UTC().Format(time.RFC3339Nano))
+	}
+
+	return args
+}
+
+// rotationArgs 构建恢复显示旋转角度的输入参数(需放在 -i 之前)
+// MPEG-TS 中间文件不保存 display matrix, 拼接时需要重新指定
+func rotationArgs(md mediaMetadata) []string {
+	if md.Rotation == 0 {
+		return nil
+	}
+
+	return []string{"-display_rotation:v:0", strconv.Itoa(md.Rotation)}
+}
+
+// setCaptureTime 将文件的访问和修改时间设为拍摄时间, t 为零值时不做修改
+func setCaptureTime(path string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+
+	if err := os.Chtimes(path, t, t); err != nil {
+		log.Printf("set mtime for %s error: %v", path, err)
+	}
+}
+
+// parseLastModified 解析浏览器提供的文件修改时间(Unix 毫秒), 无效时返回零值
+func parseLastModified(s string) time.Time {
+	ms, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,300 @@
+//
+// FilePath    : video-trim\netif.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 网络接口: 列出可用于局域网访问的地址(IPv4 和 IPv6), 选择监听地址, 并通过 mDNS/DNS-SD 广播服务
+//
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/mdns"
+)
+
+// lanAddress 一个可用于局域网访问的网卡地址
+type lanAddress struct {
+	Interface string // 网卡名称
+	IP        net.IP // 地址
+	Virtual   bool   // 可能是 Docker、虚拟机或 VPN 的虚拟网卡
+}
+
+// virtualInterfacePrefixes 常见虚拟网卡名称的前缀(小写)
+var virtualInterfacePrefixes = []string{
+	"docker", "br-", "veth", "virbr", "vmnet", "vboxnet", "vethernet", "lxc", "lxdbr", "cni", "flannel",
+	"kube", "podman", "tun", "tap", "utun", "wg", "zt", "tailscale", "awdl", "llw", "bridge",
+}
+
+// isVirtualInterface 根据名称和点对点标志判断是否可能为虚拟网卡
+func isVirtualInterface(iface net.Interface) bool {
+	if iface.Flags&net.FlagPointToPoint != 0 {
+		return true
+	}
+
+	name := strings.ToLower(iface.Name)
+
+	for _, p := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+
+	// Windows 的网卡名称, 如 "VirtualBox Host-Only Network"、"VMware Network Adapter"
+	return strings.Contains(name, "virtual") || strings.Contains(name, "vmware") || strings.Contains(name, "vpn")
+}
+
+// usableLANIP 判断地址能否在浏览器中访问: 排除回环、组播和链路本地地址(IPv6 链路本地地址需要带网卡标识, 浏览器无法使用)
+func usableLANIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsUnspecified() && !ip.IsMulticast() && !ip.IsLinkLocalUnicast()
+}
+
+// listLANAddresses 列出已启用网卡上的可用地址, 配置了 bind_interface 时只列出该网卡
+// 排序: 物理网卡在前, 同类网卡中 IPv4 在前, 其余保持系统返回的顺序
+func listLANAddresses() ([]lanAddress, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []lanAddress
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		if bindInterface != "" && iface.Name != bindInterface {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || !usableLANIP(ipnet.IP) {
+				continue
+			}
+
+			list = append(list, lanAddress{Interface: iface.Name, IP: ipnet.IP, Virtual: isVirtualInterface(iface)})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Virtual != list[j].Virtual {
+			return !list[i].Virtual
+		}
+
+		return list[i].IP.To4() != nil && list[j].IP.To4() == nil
+	})
+
+	return list, nil
+}
+
+// listenPort 返回 server_port 中的端口, 支持 ":7778"、"0.0.0.0:7778" 和 "7778"
+func listenPort() string {
+	if _, port, err := net.SplitHostPort(serverPort); err == nil {
+		return port
+	}
+
+	return strings.TrimPrefix(serverPort, ":")
+}
+
+// listenAddresses 返回 HTTP 服务的监听地址: 配置了 bind_interface 时为该网卡的每个地址, 否则为 server_port
+func listenAddresses() ([]string, error) {
+	if bindInterface == "" {
+		return []string{serverPort}, nil
+	}
+
+	list, err := listLANAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("bind_interface %q not found or has no usable address", bindInterface)
+	}
+
+	addrs := make([]string, 0, len(list))
+	for _, a := range list {
+		addrs = append(addrs, net.JoinHostPort(a.IP.String(), listenPort()))
+	}
+
+	return addrs, nil
+}
+
+// getLocalIP 返回未配置 advertise_host 时控制台提示和配对二维码使用的主机: 第一个可用地址(优先物理网卡的 IPv4)
+func getLocalIP() string {
+	if list, err := listLANAddresses(); err == nil && len(list) > 0 {
+		return list[0].IP.String()
+	}
+
+	return "127.0.0.1"
+}
+
+// hostURL 返回访问 host 的地址, IPv6 地址自动加方括号
+func hostURL(host string) string {
+	return "http://" + net.JoinHostPort(host, listenPort())
+}
+
+// advertiseURL 返回 advertise_host 的访问地址, 配置中带有端口(如端口转发、反向代理)时使用该端口
+func advertiseURL() string {
+	port := advertisePort
+	if port == "" {
+		port = listenPort()
+	}
+
+	return "http://" + net.JoinHostPort(advertiseHost, port)
+}
+
+// parseAdvertiseHost 解析 advertise_host: 主机名或 IP, 可以带端口(如 nas.lan:8080、[fe80::1]:8080), IPv6 地址可以不加方括号
+func parseAdvertiseHost(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", "", nil
+	}
+
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		host, port = s, ""
+
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			host = s[1 : len(s)-1]
+		}
+	} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", "", fmt.Errorf("invalid port %q", port)
+	}
+
+	if host == "" || strings.ContainsAny(host, "[]/ ") {
+		return "", "", fmt.Errorf("invalid host %q", host)
+	}
+
+	// 带冒号的只能是 IPv6 地址, 其余情况(如多个端口)无效
+	if strings.Contains(host, ":") {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return "", "", fmt.Errorf("invalid host %q", host)
+		}
+	}
+
+	return host, port, nil
+}
+
+// localBaseURL 返回局域网内访问本服务的地址, 用于控制台提示和配对二维码
+func localBaseURL() string {
+	if advertiseHost != "" {
+		return advertiseURL()
+	}
+
+	return hostURL(getLocalIP())
+}
+
+// printAccessURLs 打印全部可用的访问地址, 第一个即配对二维码使用的地址
+func printAccessURLs(mdnsHost string) {
+	fmt.Println("\n✅ Open in browser:")
+
+	if advertiseHost != "" {
+		fmt.Printf("   %s  (advertise_host)\n", advertiseURL())
+	}
+
+	list, err := listLANAddresses()
+	if err != nil {
+		log.Printf("list network interfaces error: %v", err)
+	}
+
+	for _, a := range list {
+		note := a.Interface
+		if a.Virtual {
+			note += ", virtual"
+		}
+
+		fmt.Printf("   %s  (%s)\n", hostURL(a.IP.String()), note)
+	}
+
+	if mdnsHost != "" {
+		fmt.Printf("   %s  (mDNS)\n", hostURL(mdnsHost))
+	}
+
+	if advertiseHost == "" && len(list) == 0 {
+		fmt.Printf("   %s\n", localBaseURL())
+	}
+
+	fmt.Println()
+}
+
+// validDNSLabel 判断是否为有效的 DNS 标签: 1-63 个字母、数字或连字符, 不以连字符开头或结尾
+func validDNSLabel(s string) bool {
+	if s == "" || len(s) > 63 || strings.HasPrefix(s, "-") || strings.HasSuffix(s, "-") {
+		return false
+	}
+
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// startMDNS 通过 mDNS 广播 <mdns_hostname>.local 的地址和 _http._tcp 服务, 返回广播的主机名
+// 只广播物理网卡的地址(没有物理网卡时广播全部地址); 广播失败只记录日志, 不影响服务
+func startMDNS() string {
+	if !mdnsEnabled {
+		return ""
+	}
+
+	list, err := listLANAddresses()
+	if err != nil || len(list) == 0 {
+		log.Printf("mdns: no usable address to advertise")
+		return ""
+	}
+
+	var ips []net.IP
+
+	for _, a := range list {
+		if !a.Virtual || list[0].Virtual {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	port, err := strconv.Atoi(listenPort())
+	if err != nil {
+		log.Printf("mdns: invalid port %q", listenPort())
+		return ""
+	}
+
+	host := mdnsHostname + ".local"
+
+	svc, err := mdns.NewMDNSService(mdnsHostname, "_http._tcp", "local.", host+".", port, ips, []string{"path=/"})
+	if err != nil {
+		log.Printf("mdns: create service error: %v", err)
+		return ""
+	}
+
+	cfg := &mdns.Config{Zone: svc}
+
+	if bindInterface != "" {
+		if iface, err := net.InterfaceByName(bindInterface); err == nil {
+			cfg.Iface = iface
+		}
+	}
+
+	if _, err := mdns.NewServer(cfg); err != nil {
+		log.Printf("mdns: start server error: %v", err)
+		return ""
+	}
+
+	log.Printf("mdns: advertising %s (%d addresses)", host, len(ips))
+
+	return host
+}
@@ -0,0 +1,79 @@
+//
+// FilePath    : video-trim\netif_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : mDNS 主机名和 advertise_host 校验的测试
+//
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidDNSLabel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "video-trim", want: true},
+		{in: "a", want: true},
+		{in: "Trim2", want: true},
+		{in: strings.Repeat("a", 63), want: true},
+		{in: strings.Repeat("a", 64), want: false},
+		{in: "", want: false},
+		{in: "-trim", want: false},
+		{in: "trim-", want: false},
+		{in: "video.trim", want: false},
+		{in: "video_trim", want: false},
+		{in: "视频", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := validDNSLabel(tt.in); got != tt.want {
+			t.Errorf("validDNSLabel(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAdvertiseHost(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantHost string
+		wantPort string
+		wantErr  bool
+	}{
+		{in: "", wantHost: "", wantPort: ""},
+		{in: " nas.lan ", wantHost: "nas.lan"},
+		{in: "192.168.1.10", wantHost: "192.168.1.10"},
+		{in: "nas.lan:8080", wantHost: "nas.lan", wantPort: "8080"},
+		{in: "192.168.1.10:5678", wantHost: "192.168.1.10", wantPort: "5678"},
+		{in: "fe80::1", wantHost: "fe80::1"},
+		{in: "[fe80::1]", wantHost: "fe80::1"},
+		{in: "[fe80::1]:8080", wantHost: "fe80::1", wantPort: "8080"},
+		{in: "fe80::1%eth0", wantHost: "fe80::1%eth0"},
+		{in: "nas.lan:0", wantErr: true},
+		{in: "nas.lan:http", wantErr: true},
+		{in: "nas.lan:8080:1", wantErr: true},
+		{in: ":8080", wantErr: true},
+		{in: "http://nas.lan", wantErr: true},
+		{in: "[nas.lan", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		host, port, err := parseAdvertiseHost(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAdvertiseHost(%q) = %q, %q, want error", tt.in, host, port)
+			}
+
+			continue
+		}
+
+		if err != nil || host != tt.wantHost || port != tt.wantPort {
+			t.Errorf("parseAdvertiseHost(%q) = %q, %q, %v; want %q, %q", tt.in, host, port, err, tt.wantHost, tt.wantPort)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+//
+// FilePath    : video-trim\pair.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 设备配对: 手机扫描包含一次性令牌的二维码后获得长期有效的设备 Cookie, 管理员可以查看和撤销已配对的设备
+//
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// 设备 Cookie 名称和有效期(浏览器允许的最长有效期约 400 天)
+const (
+	deviceCookieName = "vt_device"
+	deviceMaxAge     = 400 * 24 * time.Hour
+)
+
+// pairingToken 一次性配对令牌
+type pairingToken struct {
+	session string    // 生成二维码的会话, 扫码的设备加入该会话; 为空时设备使用自己的会话
+	expires time.Time // 过期时间
+}
+
+// pairingTokens 尚未使用的配对令牌
+var pairingTokens = struct {
+	sync.Mutex
+	m map[string]*pairingToken
+}{m: map[string]*pairingToken{}}
+
+// issuePairingToken 返回会话可用的配对令牌: 已有未过期的令牌时沿用, 否则生成新的令牌
+func issuePairingToken(session string) (string, time.Time) {
+	pairingTokens.Lock()
+	defer pairingTokens.Unlock()
+
+	now := time.Now()
+
+	for token, t := range pairingTokens.m {
+		if now.After(t.expires) {
+			delete(pairingTokens.m, token)
+		}
+	}
+
+	// 终端中的二维码(session 为空)每次都生成新的令牌
+	if session != "" {
+		for token, t := range pairingTokens.m {
+			if t.session == session {
+				return token, t.expires
+			}
+		}
+	}
+
+	token := newSessionID()
+	t := &pairingToken{session: session, expires: now.Add(time.Duration(pairingTokenMinutes) * time.Minute)}
+	pairingTokens.m[token] = t
+
+	return token, t.expires
+}
+
+// redeemPairingToken 使用配对令牌, 令牌使用后立即失效
+func redeemPairingToken(token string) (*pairingToken, bool) {
+	pairingTokens.Lock()
+	defer pairingTokens.Unlock()
+
+	t, ok := pairingTokens.m[token]
+	if !ok {
+		return nil, false
+	}
+
+	delete(pairingTokens.m, token)
+
+	return t, time.Now().Before(t.expires)
+}
+
+// pairingURL 返回手机扫码打开的配对地址
+func pairingURL(token string) string {
+	return localBaseURL() + "/pair/" + token
+}
+
+// printPairingQR 在终端打印配对二维码
+func printPairingQR() {
+	token, _ := issuePairingToken("")
+	link := pairingURL(token)
+
+	qr, err := qrcode.New(link, qrcode.Low)
+	if err != nil {
+		log.Printf("generate pairing qr code error: %v", err)
+		return
+	}
+
+	fmt.Printf("📱 Scan to pair a phone (one-time, valid for %d minutes):\n%s%s\n\n", pairingTokenMinutes, qr.ToSmallString(false), link)
+}
+
+// pairedDevice 已配对的设备
+type pairedDevice struct {
+	ID       string    `json:"id"`        // 设备 ID
+	Name     string    `json:"name"`      // 根据 User-Agent 识别的设备名称
+	Session  string    `json:"session"`   // 设备使用的会话 ID
+	IP       string    `json:"ip"`        // 最近访问的 IP
+	PairedAt time.Time `json:"paired_at"` // 配对时间
+	LastSeen time.Time `json:"last_seen"` // 最近访问时间
+}
+
+// deviceStore 已配对的设备, 保存在状态目录中, 重启后仍然有效
+type deviceStore struct {
+	mu      sync.Mutex
+	devices map[string]*pairedDevice
+}
+
+// pairedDevices 全局已配对设备表
+var pairedDevices = &deviceStore{devices: map[string]*pairedDevice{}}
+
+// load 读取已配对的设备
+func (s *deviceStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(filepath.Join(stateDir, pairedDevicesFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("read paired devices error: %v", err)
+		}
+
+		return
+	}
+
+	devices := map[string]*pairedDevice{}
+	if err := json.Unmarshal(b, &devices); err != nil {
+		log.Printf("parse paired devices error: %v", err)
+		return
+	}
+
+	s.devices = devices
+}
+
+// save 保存设备表, 调用方需持有 s.mu
+func (s *deviceStore) save() {
+	if err := saveJSONFile(filepath.Join(stateDir, pairedDevicesFile), s.devices); err != nil {
+		log.Printf("save paired devices error: %v", err)
+	}
+}
+
+// Add 登记新配对的设备
+func (s *deviceStore) Add(d *pairedDevice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.devices[d.ID] = d
+	s.save()
+}
+
+// Get 根据 ID 查找设备, 返回副本
+func (s *deviceStore) Get(id string) (pairedDevice, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.devices[id]
+	if !ok {
+		return pairedDevice{}, false
+	}
+
+	return *d, true
+}
+
+// Touch 记录设备的访问时间和 IP, 每分钟最多保存一次
+func (s *deviceStore) Touch(id, ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.devices[id]
+	if !ok || (time.Since(d.LastSeen) < time.Minute && d.IP == ip) {
+		return
+	}
+
+	d.LastSeen = time.Now()
+	d.IP = ip
+	s.save()
+}
+
+// Remove 撤销设备, 设备的会话 Cookie 随之失效; 设备不存在时返回 false
+func (s *deviceStore) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.devices[id]; !ok {
+		return false
+	}
+
+	delete(s.devices, id)
+	s.save()
+
+	return true
+}
+
+// List 返回全部设备, 最近配对的在前
+func (s *deviceStore) List() []pairedDevice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]pairedDevice, 0, len(s.devices))
+	for _, d := range s.devices {
+		list = append(list, *d)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].PairedAt.After(list[j].PairedAt) })
+
+	return list
+}
+
+// signDevice 返回设备 Cookie 的值: <设备 ID>.<签名>
+func signDevice(id string) string {
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte("device|" + id))
+
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// deviceFromRequest 返回请求携带的设备 Cookie 对应的已配对设备, 设备已撤销时返回 false
+func deviceFromRequest(r *http.Request) (pairedDevice, bool) {
+	c, err := r.Cookie(deviceCookieName)
+	if err != nil {
+		return pairedDevice{}, false
+	}
+
+	id, _, ok := strings.Cut(c.Value, ".")
+	if !ok || !hmac.Equal([]byte(c.Value), []byte(signDevice(id))) {
+		return pairedDevice{}, false
+	}
+
+	return pairedDevices.Get(id)
+}
+
+// deviceName 根据 User-Agent 粗略识别设备类型
+func deviceName(ua string) string {
+	for _, kw := range []string{"iPhone", "iPad", "Android", "Windows", "Macintosh", "Linux"} {
+		if strings.Contains(ua, kw) {
+			return kw
+		}
+	}
+
+	if len(ua) > 64 {
+		ua = ua[:64]
+	}
+
+	return strings.ToValidUTF8(ua, "")
+}
+
+// handlePairPage 显示当前会话的配对二维码, 手机扫码后与本浏览器共用会话
+func handlePairPage(w http.ResponseWriter, r *http.Request) {
+	lang := detectLangFromRequest(r)
+	i18n := getLocale(lang)
+
+	token, expires := issuePairingToken(requestSession(r))
+	link := pairingURL(token)
+
+	png, err := qrcode.Encode(link, qrcode.Medium, 256)
+	if err != nil {
+		log.Printf("generate pairing qr code error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	renderTemplate(w, http.StatusOK, "pair", struct {
+		Lang string
+		I18n map[string]string
+		Hint string
+		URL  string
+		QR   template.URL
+	}{
+		Lang: lang,
+		I18n: i18n,
+		Hint: fmt.Sprintf(i18n[KeyPairHint], int(time.Until(expires).Minutes()+0.5)),
+		URL:  link,
+		QR:   template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)),
+	})
+}
+
+// handlePairRedeem 扫码后使用配对令牌: 登记设备, 下发设备 Cookie 并进入首页
+func handlePairRedeem(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r)
+	i18n := getLocale(detectLangFromRequest(r))
+
+	// 与登录共用失败次数限制, 防止猜测令牌
+	if wait := loginAttempts.retryAfter(ip); wait > 0 {
+		respondTooManyAttempts(w, r, wait)
+		return
+	}
+
+	t, ok := redeemPairingToken(r.PathValue("token"))
+	if !ok {
+		loginAttempts.fail(ip)
+		log.Printf("pair: invalid pairing token from %s", ip)
+		http.Error(w, i18n[KeyPairInvalid], http.StatusNotFound)
+
+		return
+	}
+
+	session := t.session
+	if session == "" {
+		session = requestSession(r)
+	}
+
+	now := time.Now()
+	d := &pairedDevice{
+		ID:       newJobID(),
+		Name:     deviceName(r.UserAgent()),
+		Session:  session,
+		IP:       ip,
+		PairedAt: now,
+		LastSeen: now,
+	}
+
+	pairedDevices.Add(d)
+	log.Printf("pair: paired device %s (%s) from %s", d.ID, d.Name, ip)
+
+	setSessionCookie(w, r, session, d.ID)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     deviceCookieName,
+		Value:    signDevice(d.ID),
+		Path:     "/",
+		MaxAge:   int(deviceMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleAdminDevices 管理员查看已配对的设备; 未输入管理员令牌时显示令牌输入表单
+func handleAdminDevices(w http.ResponseWriter, r *http.Request) {
+	lang := detectLangFromRequest(r)
+	admin := isAdmin(r)
+
+	if admin && wantsJSON(r) {
+		writeJSON(w, http.StatusOK, pairedDevices.List())
+		return
+	}
+
+	status := http.StatusOK
+	if !admin {
+		status = http.StatusForbidden
+	}
+
+	renderDevices(w, r, status, lang, "")
+}
+
+// renderDevices 渲染设备管理页面
+func renderDevices(w http.ResponseWriter, r *http.Request, status int, lang, msg string) {
+	admin := isAdmin(r)
+
+	var devices []pairedDevice
+	if admin {
+		devices = pairedDevices.List()
+	}
+
+	renderTemplate(w, status, "devices", struct {
+		Lang    string
+		I18n    map[string]string
+		Enabled bool
+		Admin   bool
+		Error   string
+		Devices []pairedDevice
+	}{
+		Lang:    lang,
+		I18n:    getLocale(lang),
+		Enabled: adminToken != "",
+		Admin:   admin,
+		Error:   msg,
+		Devices: devices,
+	})
+}
+
+// handleAdminLogin 校验管理员令牌, 成功后下发与会话绑定的管理员 Cookie
+func handleAdminLogin(w http.ResponseWriter, r *http.Request) {
+	lang := detectLangFromRequest(r)
+	ip := clientIP(r)
+
+	if adminToken == "" {
+		renderDevices(w, r, http.StatusForbidden, lang, "")
+		return
+	}
+
+	if wait := loginAttempts.retryAfter(ip); wait > 0 {
+		setRetryAfter(w, wait)
+		renderDevices(w, r, http.StatusTooManyRequests, lang, loginLockedMessage(lang, wait))
+
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(adminToken)) != 1 {
+		loginAttempts.fail(ip)
+		log.Printf("auth: invalid admin token from %s", ip)
+		renderDevices(w, r, http.StatusUnauthorized, lang, getLocale(lang)[KeyAdminTokenInvalid])
+
+		return
+	}
+
+	loginAttempts.reset(ip)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookieName,
+		Value:    signAdmin(requestSession(r)),
+		Path:     "/",
+		MaxAge:   int(sessionMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	http.Redirect(w, r, "/admin/devices", http.StatusSeeOther)
+}
+
+// handleAdminDeviceDelete 撤销已配对的设备
+func handleAdminDeviceDelete(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, getLocale(detectLangFromRequest(r))[KeyAdminRequired], http.StatusForbidden)
+		return
+	}
+
+	id := r.PathValue("id")
+	if !pairedDevices.Remove(id) {
+		http.NotFound(w, r)
+		return
+	}
+
+	log.Printf("pair: revoked device %s", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,100 @@
+//
+// FilePath    : video-trim\pair_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 设备配对的测试
+//
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRevokedDeviceLosesSession(t *testing.T) {
+	sessionKey = []byte("test-session-key")
+	stateDir = t.TempDir()
+	pairedDevices = &deviceStore{devices: map[string]*pairedDevice{}}
+
+	session := newSessionID()
+	d := &pairedDevice{ID: newJobID(), Session: session, PairedAt: time.Now()}
+	pairedDevices.Add(d)
+
+	// serve 返回请求所属的会话
+	serve := func(cookies ...*http.Cookie) string {
+		var got string
+
+		h := withSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = requestSession(r)
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		return got
+	}
+
+	sessionCookie := &http.Cookie{Name: sessionCookieName, Value: signSession(session, d.ID)}
+	deviceCookie := &http.Cookie{Name: deviceCookieName, Value: signDevice(d.ID)}
+
+	if got := serve(sessionCookie, deviceCookie); got != session {
+		t.Fatalf("paired device session = %q, want %q", got, session)
+	}
+
+	if got := serve(deviceCookie); got != session {
+		t.Fatalf("paired device without session cookie = %q, want %q", got, session)
+	}
+
+	// 普通浏览器的会话 Cookie 不受设备撤销影响
+	browserCookie := &http.Cookie{Name: sessionCookieName, Value: signSession(session, "")}
+
+	pairedDevices.Remove(d.ID)
+
+	if got := serve(sessionCookie, deviceCookie); got == session {
+		t.Errorf("revoked device with both cookies still uses session %q", got)
+	}
+
+	if got := serve(sessionCookie); got == session {
+		t.Errorf("revoked device session cookie still uses session %q", got)
+	}
+
+	if got := serve(browserCookie); got != session {
+		t.Errorf("browser session = %q, want %q", got, session)
+	}
+}
+
+func TestVerifySession(t *testing.T) {
+	sessionKey = []byte("test-session-key")
+	id := newSessionID()
+
+	tests := []struct {
+		name       string
+		value      string
+		wantDevice string
+		wantOK     bool
+	}{
+		{"plain", signSession(id, ""), "", true},
+		{"device", signSession(id, "abc123"), "abc123", true},
+		{"tampered device", signSession(id, "abc123")[:33] + "abc124" + signSession(id, "abc123")[39:], "", false},
+		{"device stripped", id + signSession(id, "abc123")[39:], "", false},
+		{"short id", signSession("abc", ""), "", false},
+		{"no signature", id, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotID, gotDevice, ok := verifySession(tt.value)
+			if ok != tt.wantOK || gotDevice != tt.wantDevice || (ok && gotID != id) {
+				t.Errorf("verifySession(%q) = %q, %q, %v; want %q, %q, %v", tt.value, gotID, gotDevice, ok, id, tt.wantDevice, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+//
+// FilePath    : video-trim\phash.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 视频帧感知哈希(dHash), 用于比较不同视频的画面是否相同
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"time"
+)
+
+// dHash 使用 9x8 的灰度缩略图, 每帧 72 字节, 比较相邻像素得到 64 位哈希
+const (
+	hashWidth     = 9
+	hashHeight    = 8
+	hashFrameSize = hashWidth * hashHeight
+)
+
+// sampleFrameHashes 使用 ffmpeg 从 start 开始抽取 length 时长内的帧(每秒 fps 帧), 计算每帧的 dHash
+// length 为 0 时一直抽取到文件末尾
+func sampleFrameHashes(ctx context.Context, ffmpegPath, input string, start, length time.Duration, fps int) ([]uint64, error) {
+	args := []string{"-nostdin", "-v", "error"}
+
+	if start > 0 {
+		args = append(args, "-ss", formatFFmpegTime(start))
+	}
+
+	if length > 0 {
+		args = append(args, "-t", formatFFmpegTime(length))
+	}
+
+	// 只解码第一条视频流, 缩放为 9x8 灰度图后以原始像素输出到 stdout
+	filter := fmt.Sprintf("fps=%d,scale=%d:%d:flags=area,format=gray", fps, hashWidth, hashHeight)
+	args = append(args,
+		"-i", input,
+		"-map", "0:v:0",
+		"-an", "-sn", "-dn",
+		"-vf", filter,
+		"-f", "rawvideo",
+		"-pix_fmt", "gray",
+		"pipe:1",
+	)
+
+	cmd := newCommand(ctx, ffmpegPath, args...)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sample frames: %w", err)
+	}
+
+	hashes := make([]uint64, 0, len(out)/hashFrameSize)
+	for i := 0; i+hashFrameSize <= len(out); i += hashFrameSize {
+		hashes = append(hashes, dHash(out[i:i+hashFrameSize]))
+	}
+
+	return hashes, nil
+}
+
+// dHash 根据 9x8 灰度像素计算差值哈希: 每行比较相邻两个像素的亮度
+func dHash(px []byte) uint64 {
+	var h uint64
+
+	for y := 0; y < hashHeight; y++ {
+		row := px[y*hashWidth : (y+1)*hashWidth]
+		for x := 0; x < hashWidth-1; x++ {
+			h <<= 1
+			if row[x] < row[x+1] {
+				h |= 1
+			}
+		}
+	}
+
+	return h
+}
+
+// hammingDistance 返回两个哈希不同的位数, 越小表示画面越相似
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// minHashBits 哈希中为 1 的位数过少或过多时, 画面几乎是纯色(黑屏、白屏、纯色字幕卡)
+const minHashBits = 8
+
+// lowInformationHash 判断帧哈希是否来自几乎纯色的画面, 这类画面的哈希都接近 0, 不能用来区分不同的视频
+func lowInformationHash(h uint64) bool {
+	n := bits.OnesCount64(h)
+
+	return n < minHashBits || n > 64-minHashBits
+}
+
+// framesMatch 判断两帧哈希是否足够相似
+func framesMatch(a, b uint64) bool {
+	return hammingDistance(a, b) <= hashMatchThreshold
+}
+
+// framesToDuration 将帧数换算为时长
+func framesToDuration(frames, fps int) time.Duration {
+	if fps <= 0 {
+		return 0
+	}
+
+	return time.Duration(frames) * time.Second / time.Duration(fps)
+}
@@ -0,0 +1,128 @@
+//
+// FilePath    : video-trim\phash_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 帧哈希和片段匹配的测试
+//
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// frame 生成每行像素按 row 函数取值的 9x8 灰度帧
+func frame(row func(x int) byte) []byte {
+	px := make([]byte, 0, hashFrameSize)
+
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth; x++ {
+			px = append(px, row(x))
+		}
+	}
+
+	return px
+}
+
+func TestDHash(t *testing.T) {
+	tests := []struct {
+		name string
+		px   []byte
+		want uint64
+	}{
+		{name: "flat", px: frame(func(int) byte { return 128 }), want: 0},
+		{name: "brighter to the right", px: frame(func(x int) byte { return byte(x * 10) }), want: ^uint64(0)},
+		{name: "darker to the right", px: frame(func(x int) byte { return byte(200 - x*10) }), want: 0},
+		{name: "alternating", px: frame(func(x int) byte { return byte(x % 2 * 100) }), want: 0xAAAAAAAAAAAAAAAA},
+	}
+
+	for _, tt := range tests {
+		if got := dHash(tt.px); got != tt.want {
+			t.Errorf("%s: dHash() = %#x, want %#x", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 互相差异较大的帧哈希, 用于构造匹配测试; hashA、hashB 是纯色画面(黑屏、白屏)的哈希
+const (
+	hashA uint64 = 0
+	hashB uint64 = ^uint64(0)
+	hashC uint64 = 0xFFFFFFFF00000000
+	hashD uint64 = 0x00000000FFFFFFFF
+	hashE uint64 = 0xAAAAAAAAAAAAAAAA
+	hashF uint64 = 0x0F0F0F0F0F0F0F0F
+)
+
+func TestFindClip(t *testing.T) {
+	tests := []struct {
+		name      string
+		window    []uint64
+		clip      []uint64
+		wantPos   int
+		wantScore float64
+		wantOK    bool
+	}{
+		{name: "exact match", window: []uint64{hashA, hashB, hashC, hashD}, clip: []uint64{hashC, hashD}, wantPos: 2, wantOK: true},
+		{name: "near match", window: []uint64{hashA, hashB, hashC ^ 0b11, hashD}, clip: []uint64{hashC, hashD}, wantPos: 2, wantScore: 1, wantOK: true},
+		{name: "earliest of equal matches", window: []uint64{hashC, hashC, hashC}, clip: []uint64{hashC}, wantPos: 0, wantOK: true},
+		{name: "no match", window: []uint64{hashA, hashA, hashA}, clip: []uint64{hashB}, wantOK: false},
+		{name: "window shorter than clip", window: []uint64{hashA}, clip: []uint64{hashA, hashA}, wantOK: false},
+		{name: "empty clip", window: []uint64{hashA}, clip: nil, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		pos, score, ok := findClip(tt.window, tt.clip)
+		if ok != tt.wantOK || (ok && (pos != tt.wantPos || score != tt.wantScore)) {
+			t.Errorf("%s: findClip() = %d, %v, %v, want %d, %v, %v", tt.name, pos, score, ok, tt.wantPos, tt.wantScore, tt.wantOK)
+		}
+	}
+}
+
+func TestCommonIntroLengths(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes [][]uint64
+		fps    int
+		want   []time.Duration
+	}{
+		{
+			name:   "shared intro",
+			hashes: [][]uint64{{hashC, hashD, hashC, hashE}, {hashC, hashD, hashC, hashF}, {hashD, hashC}},
+			fps:    2,
+			want:   []time.Duration{secs(1.5), secs(1.5), 0},
+		},
+		{
+			name:   "shared prefix shorter than a second",
+			hashes: [][]uint64{{hashC, hashE}, {hashC, hashF}},
+			fps:    2,
+			want:   []time.Duration{0, 0},
+		},
+		{
+			name:   "both fade in from black",
+			hashes: [][]uint64{{hashA, hashA, hashA, hashC}, {hashA, hashA, hashA, hashD}},
+			fps:    2,
+			want:   []time.Duration{0, 0},
+		},
+		{
+			name:   "fade in from black before a shared logo",
+			hashes: [][]uint64{{hashA, hashA, hashC, hashD, hashE}, {hashA, hashA, hashC, hashD, hashF}},
+			fps:    2,
+			want:   []time.Duration{secs(2), secs(2)},
+		},
+		{
+			name:   "single file",
+			hashes: [][]uint64{{hashA, hashB}},
+			fps:    2,
+			want:   []time.Duration{0},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := commonIntroLengths(tt.hashes, tt.fps); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: commonIntroLengths() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+//
+// FilePath    : video-trim\precise.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 精确剪切(smart render): 只重新编码剪切点到下一个关键帧之间的画面, 其余部分流复制
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// snapPrecise 精确到帧: 剪切点到下一个关键帧之间重新编码, 其余部分流复制
+const snapPrecise = "precise"
+
+// smartEncoders 支持精确剪切的视频编码及对应的 ffmpeg 编码器
+var smartEncoders = map[string]string{
+	"h264": "libx264",
+	"hevc": "libx265",
+}
+
+// smartProfiles ffprobe 输出的档次名称对应的编码器 -profile:v 参数
+// 未列出的档次(如 H.264 Extended、Intra 系列, HEVC Rext、SCC)编码器不支持或需要额外参数, 不指定档次, 由编码器根据像素格式选择
+var smartProfiles = map[string]map[string]string{
+	"h264": {
+		"Baseline":              "baseline",
+		"Constrained Baseline":  "baseline",
+		"Main":                  "main",
+		"High":                  "high",
+		"Progressive High":      "high",
+		"Constrained High":      "high",
+		"High 10":               "high10",
+		"High 4:2:2":            "high422",
+		"High 4:4:4 Predictive": "high444",
+	},
+	"hevc": {
+		"Main":               "main",
+		"Main 10":            "main10",
+		"Main Still Picture": "mainstillpicture",
+	},
+}
+
+// videoStreamInfo 第一条视频流的编码参数, 重新编码时需要与原视频保持一致才能无缝拼接
+type videoStreamInfo struct {
+	CodecName string `json:"codec_name"` // 编码, 如 h264
+	Profile   string `json:"profile"`    // 档次, 如 High
+	PixFmt    string `json:"pix_fmt"`    // 像素格式, 如 yuv420p
+}
+
+// renderPiece 导出的一段片段, Encode 不为空时重新编码视频, 否则流复制
+type renderPiece struct {
+	timeRange
+	Encode []string // 重新编码视频使用的 ffmpeg 编码参数
+}
+
+// copyPieces 将片段全部标记为流复制
+func copyPieces(segments []timeRange) []renderPiece {
+	pieces := make([]renderPiece, 0, len(segments))
+	for _, s := range segments {
+		pieces = append(pieces, renderPiece{timeRange: s})
+	}
+
+	return pieces
+}
+
+// probeVideoStream 使用 ffprobe 获取第一条视频流的编码参数
+func probeVideoStream(ctx context.Context, absInput string) (*videoStreamInfo, error) {
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	cmd := newCommand(ctx, ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name,profile,pix_fmt",
+		"-of", "json",
+		absInput,
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("probe video stream: %w", err)
+	}
+
+	var res struct {
+		Streams []videoStreamInfo `json:"streams"`
+	}
+
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("parse video stream: %w", err)
+	}
+
+	if len(res.Streams) == 0 {
+		return nil, fmt.Errorf("no video stream")
+	}
+
+	return &res.Streams[0], nil
+}
+
+// smartEncodeArgs 根据原视频参数生成重新编码使用的参数, 不支持的编码返回错误
+func smartEncodeArgs(info *videoStreamInfo) ([]string, error) {
+	encoder, ok := smartEncoders[info.CodecName]
+	if !ok {
+		return nil, fmt.Errorf("precise cut does not support %q video", info.CodecName)
+	}
+
+	args := []string{"-c:v", encoder, "-preset", "veryfast", "-crf", "18"}
+
+	// ffprobe 输出的档次名称(如 "High 10"、"Constrained Baseline")转换为编码器参数(high10、baseline)
+	if p, ok := smartProfiles[info.CodecName][info.Profile]; ok {
+		args = append(args, "-profile:v", p)
+	}
+
+	if info.PixFmt != "" {
+		args = append(args, "-pix_fmt", info.PixFmt)
+	}
+
+	return args, nil
+}
+
+// precisePieces 将片段在起点之后的第一个关键帧处拆分: 前一部分重新编码, 后一部分流复制
+// 起点已在关键帧上的片段直接流复制
+func precisePieces(segments []timeRange, keyframes []time.Duration, encode []string) []renderPiece {
+	pieces := []renderPiece{}
+
+	for _, s := range segments {
+		next := snapTime(s.Start, keyframes, snapForward)
+
+		// 起点在关键帧上, 或之后没有关键帧(只能整段重新编码)
+		switch {
+		case next == s.Start:
+			pieces = append(pieces, renderPiece{timeRange: s})
+			continue
+		case next < s.Start:
+			pieces = append(pieces, renderPiece{timeRange: s, Encode: encode})
+			continue
+		}
+
+		// 整段都在下一个关键帧之前, 全部重新编码
+		if s.End > 0 && next >= s.End {
+			pieces = append(pieces, renderPiece{timeRange: s, Encode: encode})
+			continue
+		}
+
+		// 距离关键帧过近时不值得重新编码, 直接从关键帧开始
+		if next-s.Start >= minSegment {
+			pieces = append(pieces, renderPiece{timeRange: timeRange{Start: s.Start, End: next}, Encode: encode})
+		}
+
+		pieces = append(pieces, renderPiece{timeRange: timeRange{Start: next, End: s.End}})
+	}
+
+	return pieces
+}
+
+// planPrecisePieces 为精确剪切生成导出片段, 无法重新编码时返回错误由调用方退回到关键帧对齐
+func planPrecisePieces(ctx context.Context, absInput string, segments []timeRange, keyframes []time.Duration) ([]renderPiece, error) {
+	info, err := probeVideoStream(ctx, absInput)
+	if err != nil {
+		return nil, err
+	}
+
+	encode, err := smartEncodeArgs(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return precisePieces(segments, keyframes, encode), nil
+}
+
+// buildSmartEncodeArgs 构建重新编码一段视频的 ffmpeg 参数: 视频按 encode 重新编码, 其余流复制
+// 重新编码时输入前的 -ss 会从之前的关键帧开始解码并丢弃剪切点之前的帧, 因此精确到帧
+func buildSmartEncodeArgs(absInput, absOutput string, seg timeRange, encode, streamArgs []string) []string {
+	args := []string{
+		"-ss", formatFFmpegTime(seg.Start),
+		"-i", absInput,
+	}
+
+	if seg.End > 0 {
+		args = append(args, "-t", formatFFmpegTime(seg.Len()))
+	}
+
+	args = append(args, "-c", "copy")
+	args = append(args, encode...)
+	args = append(args, streamArgs...)
+
+	return append(args,
+		"-avoid_negative_ts", "make_zero",
+		absOutput,
+	)
+}
+
+// hasEncodedPiece 判断是否有需要重新编码的片段
+func hasEncodedPiece(pieces []renderPiece) bool {
+	for _, p := range pieces {
+		if len(p.Encode) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildPieceArgs 根据片段类型构建流复制或重新编码的 ffmpeg 参数, ts 为 true 时输出 MPEG-TS 中间文件
+func buildPieceArgs(absInput, absOutput string, p renderPiece, streamArgs []string, ts bool) []string {
+	var args []string
+	if len(p.Encode) > 0 {
+		args = buildSmartEncodeArgs(absInput, absOutput, p.timeRange, p.Encode, streamArgs)
+	} else {
+		args = buildFFmpegArgs(absInput, absOutput, p.timeRange, streamArgs)
+	}
+
+	if !ts {
+		return args
+	}
+
+	// MPEG-TS 不支持大多数字幕编码, 中间文件只保留音视频
+	n := len(args) - 1
+
+	return append(append(args[:n:n], "-sn", "-dn", "-f", "mpegts"), args[n])
+}
@@ -0,0 +1,99 @@
+//
+// FilePath    : video-trim\precise_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 精确剪切片段拆分和重新编码参数的测试
+//
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPrecisePieces(t *testing.T) {
+	keyframes := []time.Duration{0, secs(2), secs(4), secs(6)}
+	encode := []string{"-c:v", "libx264"}
+
+	copyPiece := func(start, end time.Duration) renderPiece {
+		return renderPiece{timeRange: timeRange{start, end}}
+	}
+
+	encodePiece := func(start, end time.Duration) renderPiece {
+		return renderPiece{timeRange: timeRange{start, end}, Encode: encode}
+	}
+
+	tests := []struct {
+		name    string
+		segment timeRange
+		want    []renderPiece
+	}{
+		{name: "start on keyframe", segment: timeRange{secs(2), secs(5)}, want: []renderPiece{copyPiece(secs(2), secs(5))}},
+		{name: "split at next keyframe", segment: timeRange{secs(1), secs(5)}, want: []renderPiece{encodePiece(secs(1), secs(2)), copyPiece(secs(2), secs(5))}},
+		{name: "ends before next keyframe", segment: timeRange{secs(2.5), secs(3.5)}, want: []renderPiece{encodePiece(secs(2.5), secs(3.5))}},
+		{name: "too close to keyframe", segment: timeRange{secs(1.995), secs(5)}, want: []renderPiece{copyPiece(secs(2), secs(5))}},
+		{name: "after last keyframe", segment: timeRange{secs(7), secs(9)}, want: []renderPiece{encodePiece(secs(7), secs(9))}},
+		{name: "open segment", segment: timeRange{Start: secs(1)}, want: []renderPiece{encodePiece(secs(1), secs(2)), copyPiece(secs(2), 0)}},
+	}
+
+	for _, tt := range tests {
+		if got := precisePieces([]timeRange{tt.segment}, keyframes, encode); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: precisePieces() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSmartEncodeArgs(t *testing.T) {
+	tests := []struct {
+		info    videoStreamInfo
+		want    []string
+		wantErr bool
+	}{
+		{
+			info: videoStreamInfo{CodecName: "h264", Profile: "Constrained Baseline", PixFmt: "yuv420p"},
+			want: []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "18", "-profile:v", "baseline", "-pix_fmt", "yuv420p"},
+		},
+		{
+			info: videoStreamInfo{CodecName: "h264", Profile: "High 4:4:4 Predictive", PixFmt: "yuv444p"},
+			want: []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "18", "-profile:v", "high444", "-pix_fmt", "yuv444p"},
+		},
+		{
+			info: videoStreamInfo{CodecName: "h264", Profile: "Extended", PixFmt: "yuv420p"},
+			want: []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "18", "-pix_fmt", "yuv420p"},
+		},
+		{
+			info: videoStreamInfo{CodecName: "hevc", Profile: "Main 10", PixFmt: "yuv420p10le"},
+			want: []string{"-c:v", "libx265", "-preset", "veryfast", "-crf", "18", "-profile:v", "main10", "-pix_fmt", "yuv420p10le"},
+		},
+		{
+			info: videoStreamInfo{CodecName: "hevc", Profile: "Rext", PixFmt: "yuv422p10le"},
+			want: []string{"-c:v", "libx265", "-preset", "veryfast", "-crf", "18", "-pix_fmt", "yuv422p10le"},
+		},
+		{
+			info: videoStreamInfo{CodecName: "hevc", Profile: "unknown"},
+			want: []string{"-c:v", "libx265", "-preset", "veryfast", "-crf", "18"},
+		},
+		{
+			info:    videoStreamInfo{CodecName: "vp9", Profile: "Profile 0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := smartEncodeArgs(&tt.info)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("smartEncodeArgs(%+v) = %v, want error", tt.info, got)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("smartEncodeArgs(%+v) = %v, %v; want %v", tt.info, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+//
+// FilePath    : video-trim\privacy.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 隐私清理: 在流复制的同时去掉位置、设备、编码器和用户备注等元数据, 并报告去掉了哪些标签
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// privacyTag 原视频中需要去掉的元数据标签
+type privacyTag struct {
+	Key    string // 标签名
+	Stream int    // 所在流的序号, -1 表示全局标签
+}
+
+// String 返回展示用的标签描述
+func (t privacyTag) String() string {
+	if t.Stream < 0 {
+		return t.Key
+	}
+
+	return fmt.Sprintf("%s (stream #%d)", t.Key, t.Stream)
+}
+
+// 按标签名最后一段(如 com.apple.quicktime.model 的 model)判断的隐私标签
+var privacyKeySuffixes = map[string]bool{
+	// 设备
+	"make":         true,
+	"model":        true,
+	"manufacturer": true,
+	"device":       true,
+	"camera":       true,
+	"lens":         true,
+	"serial":       true,
+	// 编码器与软件
+	"encoder":       true,
+	"encoded_by":    true,
+	"encoding_tool": true,
+	"software":      true,
+	// 用户备注
+	"comment":      true,
+	"user_comment": true,
+	"description":  true,
+	"synopsis":     true,
+}
+
+// isPrivacyTag 判断元数据标签是否包含位置、设备、编码器或用户备注信息
+func isPrivacyTag(key string) bool {
+	k := strings.ToLower(key)
+
+	// 位置: location、location-eng、com.apple.quicktime.location.ISO6709、©xyz 等
+	if strings.Contains(k, "location") || strings.Contains(k, "gps") || strings.HasSuffix(k, "xyz") || strings.HasSuffix(k, "iso6709") {
+		return true
+	}
+
+	// Android 设备写入的系统版本
+	if k == "com.android.version" {
+		return true
+	}
+
+	// 带语言后缀的标签(如 comment-eng)按去掉后缀后的名称判断
+	if i := strings.LastIndex(k, "."); i >= 0 {
+		k = k[i+1:]
+	}
+
+	if i := strings.Index(k, "-"); i > 0 {
+		k = k[:i]
+	}
+
+	return privacyKeySuffixes[k]
+}
+
+// probeTags 使用 ffprobe 列出全局和各个流的全部元数据标签
+func probeTags(ctx context.Context, absInput string) (map[string]string, map[int]map[string]string, error) {
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, nil, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	cmd := newCommand(ctx, ffprobePath,
+		"-v", "error",
+		"-show_entries", "format_tags:stream=index:stream_tags",
+		"-of", "json",
+		absInput,
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("probe tags: %w", err)
+	}
+
+	var res struct {
+		Format struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"format"`
+		Streams []struct {
+			Index int               `json:"index"`
+			Tags  map[string]string `json:"tags"`
+		} `json:"streams"`
+	}
+
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, nil, fmt.Errorf("parse tags: %w", err)
+	}
+
+	streams := map[int]map[string]string{}
+	for _, s := range res.Streams {
+		streams[s.Index] = s.Tags
+	}
+
+	return res.Format.Tags, streams, nil
+}
+
+// findPrivacyTags 找出需要去掉的全局和流标签, 按位置和名称排序
+func findPrivacyTags(global map[string]string, streams map[int]map[string]string) []privacyTag {
+	var found []privacyTag
+
+	for k := range global {
+		if isPrivacyTag(k) {
+			found = append(found, privacyTag{Key: k, Stream: -1})
+		}
+	}
+
+	for idx, tags := range streams {
+		for k := range tags {
+			if isPrivacyTag(k) {
+				found = append(found, privacyTag{Key: k, Stream: idx})
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Stream != found[j].Stream {
+			return found[i].Stream < found[j].Stream
+		}
+
+		return found[i].Key < found[j].Key
+	})
+
+	return found
+}
+
+// privacyArgs 构建去掉隐私标签的输出参数
+// 输出流的序号与原视频不同, 流标签对所有输出流清空(值为空即删除标签);
+// bitexact 阻止 ffmpeg 写入自己的 encoder 标签
+func privacyArgs(found []privacyTag) []string {
+	args := []string{"-fflags", "+bitexact"}
+	seen := map[string]bool{}
+
+	for _, t := range found {
+		opt := "-metadata"
+		if t.Stream >= 0 {
+			opt = "-metadata:s"
+		}
+
+		if seen[opt+t.Key] {
+			continue
+		}
+
+		seen[opt+t.Key] = true
+		args = append(args, opt, t.Key+"=")
+	}
+
+	return args
+}
+
+// planPrivacyScrub 探测原视频的元数据, 返回清理参数和被去掉的标签描述
+func planPrivacyScrub(ctx context.Context, absInput string) ([]string, []string, error) {
+	global, streams, err := probeTags(ctx, absInput)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := findPrivacyTags(global, streams)
+
+	removed := make([]string, 0, len(found))
+	for _, t := range found {
+		removed = append(removed, t.String())
+	}
+
+	return privacyArgs(found), removed, nil
+}
+
+// parseScrub 解析表单中的隐私清理开关, 支持 1/0、true/false 和复选框的 on
+func parseScrub(s string) (bool, error) {
+	if strings.EqualFold(s, "on") {
+		return true, nil
+	}
+
+	return strconv.ParseBool(s)
+}
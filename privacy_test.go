@@ -0,0 +1,41 @@
+//
+// FilePath    : video-trim\privacy_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 隐私元数据标签识别的测试
+//
+
+package main
+
+import "testing"
+
+func TestIsPrivacyTag(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "location", want: true},
+		{key: "location-eng", want: true},
+		{key: "com.apple.quicktime.location.ISO6709", want: true},
+		{key: "©xyz", want: true},
+		{key: "GPSCoordinates", want: true},
+		{key: "com.apple.quicktime.make", want: true},
+		{key: "com.apple.quicktime.model", want: true},
+		{key: "com.android.version", want: true},
+		{key: "encoder", want: true},
+		{key: "comment-eng", want: true},
+		{key: "creation_time", want: false},
+		{key: "com.apple.quicktime.creationdate", want: false},
+		{key: "rotate", want: false},
+		{key: "handler_name", want: false},
+		{key: "language", want: false},
+		{key: "title", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isPrivacyTag(tt.key); got != tt.want {
+			t.Errorf("isPrivacyTag(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+//
+// FilePath    : video-trim\progress.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 解析 ffmpeg -progress 输出, 计算处理进度
+//
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ffmpegStderrLimit 保留的 ffmpeg 错误输出字节数, 用于失败时的错误信息
+const ffmpegStderrLimit = 4096
+
+// ffmpegProgress ffmpeg 处理进度
+type ffmpegProgress struct {
+	Percent float64 // 完成百分比(0-100), 总时长未知时为 0
+	Speed   float64 // 处理速度倍率, 例如 25 表示 25 倍速
+	ETA     float64 // 预计剩余时间(秒), 无法估算时为 0
+}
+
+// progressFunc 进度回调函数
+type progressFunc func(p ffmpegProgress)
+
+// tailBuffer 只保留最后 limit 个字节的写入缓冲, 避免 ffmpeg 日志占用过多内存
+type tailBuffer struct {
+	limit int
+	buf   []byte
+}
+
+// Write 实现 io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.limit {
+		t.buf = t.buf[len(t.buf)-t.limit:]
+	}
+
+	return len(p), nil
+}
+
+// String 返回缓冲内容
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
+
+// runFFmpegCommand 执行带 -progress pipe:1 参数的 ffmpeg 命令, 解析进度并回调
+// total 为预计输出时长(秒), 用于计算百分比和剩余时间
+func runFFmpegCommand(cmd *exec.Cmd, total float64, onProgress progressFunc) error {
+	stderr := &tailBuffer{limit: ffmpegStderrLimit}
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanProgress(stdout, total, onProgress)
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// scanProgress 逐行读取 ffmpeg 的 key=value 进度输出, 每个 progress 块回调一次
+func scanProgress(r io.Reader, total float64, onProgress progressFunc) {
+	var outTime, speed float64
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "out_time_us":
+			if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 {
+				outTime = v / 1e6
+			}
+		case "speed":
+			if v, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+				speed = v
+			}
+		case "progress":
+			if onProgress == nil {
+				continue
+			}
+
+			p := calcProgress(outTime, speed, total)
+			if value == "end" {
+				p.Percent = 100
+				p.ETA = 0
+			}
+
+			onProgress(p)
+		}
+	}
+
+	// 读取剩余输出, 防止 ffmpeg 因管道写满而阻塞
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// calcProgress 根据已处理时长、速度和总时长计算进度
+func calcProgress(outTime, speed, total float64) ffmpegProgress {
+	p := ffmpegProgress{Speed: speed}
+	if total <= 0 {
+		return p
+	}
+
+	p.Percent = outTime / total * 100
+	if p.Percent > 100 {
+		p.Percent = 100
+	}
+
+	if speed > 0 {
+		p.ETA = (total - outTime) / speed
+		if p.ETA < 0 {
+			p.ETA = 0
+		}
+	}
+
+	return p
+}
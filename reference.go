@@ -0,0 +1,401 @@
+//
+// FilePath    : video-trim\reference.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 参考片段(已知的片头/片尾): 注册、指纹计算及在上传视频中的匹配
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trimModeReference 使用参考片段匹配结果作为掐头/去尾位置
+const trimModeReference = "reference"
+
+// referenceNamePattern 参考片段名称只允许字母、数字、下划线和短横线, 防止路径遍历
+var referenceNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,64}$`)
+
+// referenceClip 参考片段的指纹
+type referenceClip struct {
+	Name      string    `json:"name"`       // 名称, 同时作为文件名
+	File      string    `json:"file"`       // 片段文件名(位于 referenceDir)
+	Duration  float64   `json:"duration"`   // 片段时长(秒)
+	FPS       int       `json:"fps"`        // 计算指纹时每秒抽取的帧数
+	Hashes    []uint64  `json:"hashes"`     // 每帧的 dHash
+	CreatedAt time.Time `json:"created_at"` // 注册时间
+}
+
+// referenceMatch 参考片段在视频中的匹配结果
+type referenceMatch struct {
+	Name  string        // 匹配到的参考片段名称
+	Start time.Duration // 匹配开始位置
+	End   time.Duration // 匹配结束位置
+	Score float64       // 平均差异位数, 越小越相似
+}
+
+// fingerprintPath 返回参考片段指纹文件路径
+func fingerprintPath(name string) string {
+	return filepath.Join(referenceDir, name+".json")
+}
+
+// loadReferenceClips 读取 referenceDir 下所有参考片段指纹
+func loadReferenceClips() ([]*referenceClip, error) {
+	files, err := filepath.Glob(filepath.Join(referenceDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	clips := []*referenceClip{}
+
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			log.Printf("read reference %s error: %v", f, err)
+			continue
+		}
+
+		var clip referenceClip
+		if err := json.Unmarshal(b, &clip); err != nil {
+			log.Printf("parse reference %s error: %v", f, err)
+			continue
+		}
+
+		clips = append(clips, &clip)
+	}
+
+	sort.Slice(clips, func(i, j int) bool { return clips[i].Name < clips[j].Name })
+
+	return clips, nil
+}
+
+// registerReferenceClip 将已上传到 referenceDir 的临时文件 src 登记为参考片段并计算其指纹
+func registerReferenceClip(ctx context.Context, name, filename, src string) (*referenceClip, error) {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		ext = ".mp4"
+	}
+
+	clipFile := name + ext
+	clipPath := filepath.Join(referenceDir, clipFile)
+
+	if err := os.Rename(src, clipPath); err != nil {
+		return nil, err
+	}
+
+	// 参考片段需要整段计算指纹
+	hashes, err := sampleFrameHashes(ctx, ffmpegPath, clipPath, 0, 0, hashSampleFPS)
+	if err != nil {
+		os.Remove(clipPath)
+		return nil, err
+	}
+
+	// 片段过短无法可靠匹配, 过长则无法在搜索窗口内完整出现
+	window := referenceSearchSeconds * hashSampleFPS
+	if len(hashes) < hashSampleFPS || len(hashes) > window {
+		os.Remove(clipPath)
+		return nil, fmt.Errorf("reference clip must be between 1 and %d seconds", referenceSearchSeconds)
+	}
+
+	clip := &referenceClip{
+		Name:      name,
+		File:      clipFile,
+		Duration:  framesToDuration(len(hashes), hashSampleFPS).Seconds(),
+		FPS:       hashSampleFPS,
+		Hashes:    hashes,
+		CreatedAt: time.Now(),
+	}
+
+	b, err := json.Marshal(clip)
+	if err != nil {
+		os.Remove(clipPath)
+		return nil, err
+	}
+
+	if err := os.WriteFile(fingerprintPath(name), b, 0600); err != nil {
+		os.Remove(clipPath)
+		return nil, err
+	}
+
+	return clip, nil
+}
+
+// deleteReferenceClip 删除参考片段及其指纹
+func deleteReferenceClip(name string) error {
+	b, err := os.ReadFile(fingerprintPath(name))
+	if err != nil {
+		return err
+	}
+
+	var clip referenceClip
+	if err := json.Unmarshal(b, &clip); err == nil && clip.File != "" {
+		os.Remove(filepath.Join(referenceDir, filepath.Base(clip.File)))
+	}
+
+	return os.Remove(fingerprintPath(name))
+}
+
+// findClip 在 window 帧序列中查找与 clip 最相似的位置, 返回起始帧和平均差异位数
+// 平均差异超过 hashMatchThreshold 时返回 false
+func findClip(window, clip []uint64) (int, float64, bool) {
+	if len(clip) == 0 || len(window) < len(clip) {
+		return 0, 0, false
+	}
+
+	bestPos, bestScore := -1, float64(hashMatchThreshold)
+
+	for pos := 0; pos+len(clip) <= len(window); pos++ {
+		sum := 0
+		for i, h := range clip {
+			sum += hammingDistance(window[pos+i], h)
+		}
+
+		score := float64(sum) / float64(len(clip))
+		if score <= bestScore && (bestPos < 0 || score < bestScore) {
+			bestPos, bestScore = pos, score
+		}
+	}
+
+	if bestPos < 0 {
+		return 0, 0, false
+	}
+
+	return bestPos, bestScore, true
+}
+
+// matchReferences 在视频开头和结尾的搜索窗口中查找参考片段
+// 返回开头匹配(片头)和结尾匹配(片尾), 未匹配时对应结果为 nil
+func matchReferences(ctx context.Context, ffmpegPath, input string, duration time.Duration, clips []*referenceClip) (*referenceMatch, *referenceMatch, error) {
+	window := time.Duration(referenceSearchSeconds) * time.Second
+
+	headHashes, err := sampleFrameHashes(ctx, ffmpegPath, input, 0, window, hashSampleFPS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	intro := bestMatch(headHashes, 0, clips)
+
+	// 结尾窗口与开头窗口重叠时, 只在片头之后查找片尾
+	tailStart := max(duration-window, 0)
+	if intro != nil {
+		tailStart = max(tailStart, intro.End)
+	}
+
+	if duration <= 0 || tailStart >= duration {
+		return intro, nil, nil
+	}
+
+	tailHashes, err := sampleFrameHashes(ctx, ffmpegPath, input, tailStart, 0, hashSampleFPS)
+	if err != nil {
+		return intro, nil, err
+	}
+
+	return intro, bestMatch(tailHashes, tailStart, clips), nil
+}
+
+// bestMatch 返回所有参考片段在 hashes 中差异最小的匹配, offset 为 hashes 第一帧对应的时间
+func bestMatch(hashes []uint64, offset time.Duration, clips []*referenceClip) *referenceMatch {
+	var best *referenceMatch
+
+	for _, clip := range clips {
+		// 抽帧频率不同的指纹无法直接比较
+		if clip.FPS != hashSampleFPS {
+			continue
+		}
+
+		pos, score, ok := findClip(hashes, clip.Hashes)
+		if !ok || (best != nil && score >= best.Score) {
+			continue
+		}
+
+		best = &referenceMatch{
+			Name:  clip.Name,
+			Start: offset + framesToDuration(pos, clip.FPS),
+			End:   offset + framesToDuration(pos+len(clip.Hashes), clip.FPS),
+			Score: score,
+		}
+	}
+
+	return best
+}
+
+// matchJobReferences 对任务中的每个文件匹配参考片段, 结果写入 DetectedHead/DetectedTail
+// 未匹配到的文件使用固定的掐头/去尾时长, 并标记 NoIntroMatch/NoOutroMatch 在结果页面提示
+func matchJobReferences(job *Job) {
+	clips, err := loadReferenceClips()
+	if err != nil || len(clips) == 0 {
+		log.Printf("job %s: no reference clips available: %v", job.ID, err)
+		markNoReferenceMatch(job)
+
+		return
+	}
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		log.Printf("job %s: reference matching skipped, ffmpeg not found in PATH: %v", job.ID, err)
+		markNoReferenceMatch(job)
+
+		return
+	}
+
+	for _, f := range job.Files {
+		if job.ctx.Err() != nil {
+			return
+		}
+
+		duration, err := probeDurationForTrim(job.ctx, f.inputPath, true)
+		if err != nil {
+			log.Printf("job %s: reference matching for %s error: %v", job.ID, f.Name, err)
+			job.update(func(*Job) { f.NoIntroMatch, f.NoOutroMatch = true, true })
+
+			continue
+		}
+
+		intro, outro, err := matchReferences(job.ctx, ffmpegPath, f.inputPath, duration, clips)
+		if err != nil {
+			log.Printf("job %s: reference matching for %s error: %v", job.ID, f.Name, err)
+		}
+
+		job.update(func(*Job) {
+			if intro != nil {
+				f.DetectedHead = intro.End.Seconds()
+				f.MatchedIntro = intro.Name
+			}
+
+			if outro != nil {
+				f.DetectedTail = (duration - outro.Start).Seconds()
+				f.MatchedOutro = outro.Name
+			}
+
+			f.NoIntroMatch = intro == nil
+			f.NoOutroMatch = outro == nil
+		})
+	}
+}
+
+// markNoReferenceMatch 无法匹配参考片段时将任务中的全部文件标记为未匹配
+func markNoReferenceMatch(job *Job) {
+	job.update(func(j *Job) {
+		for _, f := range j.Files {
+			f.NoIntroMatch = true
+			f.NoOutroMatch = true
+		}
+	})
+}
+
+// handleReferences 返回已注册的参考片段列表(不含指纹数据)
+func handleReferences(w http.ResponseWriter, r *http.Request) {
+	clips, err := loadReferenceClips()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, c := range clips {
+		c.Hashes = nil
+	}
+
+	writeJSON(w, http.StatusOK, clips)
+}
+
+// handleReferenceUpload 注册新的参考片段, 表单字段: name(名称), clip(视频文件)
+func handleReferenceUpload(w http.ResponseWriter, r *http.Request) {
+	lang := detectLangFromRequest(r)
+	i18n := getLocale(lang)
+
+	if !checkDiskSpaceOrRespond(w, r, r.ContentLength) {
+		return
+	}
+
+	// 片段先写入参考片段目录下的临时文件, 登记时再重命名
+	form, ok := readUploadFormOrRespond(w, r, "clip", func(_ int, filename string) string {
+		return filepath.Join(referenceDir, fmt.Sprintf(".upload_%s%s", newJobID(), uploadExt(filename)))
+	})
+	if !ok {
+		return
+	}
+
+	registered := false
+	defer func() {
+		if !registered {
+			form.RemoveFiles()
+		}
+	}()
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if !referenceNamePattern.MatchString(name) {
+		http.Error(w, i18n[KeyInvalidReferenceName], http.StatusBadRequest)
+		return
+	}
+
+	if _, err := os.Stat(fingerprintPath(name)); err == nil {
+		http.Error(w, i18n[KeyReferenceExists], http.StatusConflict)
+		return
+	}
+
+	if len(form.Files) != 1 {
+		http.Error(w, i18n[KeySelectAtLeastOne], http.StatusBadRequest)
+		return
+	}
+
+	upload := form.Files[0]
+
+	clip, err := registerReferenceClip(r.Context(), name, upload.Filename, upload.Path)
+	registered = err == nil
+
+	if err != nil {
+		log.Printf("register reference %s error: %v", name, err)
+		http.Error(w, i18n[KeyReferenceFailed]+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	clip.Hashes = nil
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusCreated, clip)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleReferenceDelete 删除参考片段
+func handleReferenceDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !referenceNamePattern.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := deleteReferenceClip(name); err != nil {
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,298 @@
+//
+// FilePath    : video-trim\retention.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 自动清理: 按保留时间和输出目录总大小删除旧文件, 启动时清理上次运行残留的临时文件
+//
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// removeOutput 删除输出目录中的文件, 并清理缩略图、时长缓存和任务中的下载链接
+func removeOutput(name string) error {
+	path, err := resolveLibraryPath(name)
+	if err != nil {
+		return err
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return os.ErrNotExist
+	}
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	removeLibraryCache(name, info)
+	outputOwners.Rename(name, "")
+	jobs.renameOutput(name, "")
+
+	return nil
+}
+
+// expireOutput 按清理规则删除输出文件并记录日志, 返回是否已删除
+func expireOutput(name, reason string) bool {
+	if err := removeOutput(name); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("retention: remove output %s error: %v", name, err)
+		}
+
+		return false
+	}
+
+	log.Printf("retention: removed output %s (%s)", name, reason)
+
+	return true
+}
+
+// removeLogged 删除临时文件并记录日志
+func removeLogged(path, reason string) {
+	if err := os.RemoveAll(path); err != nil {
+		log.Printf("retention: remove %s error: %v", path, err)
+		return
+	}
+
+	log.Printf("retention: removed %s (%s)", path, reason)
+}
+
+// cleanOrphans 启动时清理上次运行残留的临时文件
+// 任务和暂存记录只保存在内存中, 重启后任务工作目录、暂存和雪碧图文件不会再被使用;
+// 保留可以继续的断点续传上传, 以及仍对应输出文件的缩略图
+func cleanOrphans() {
+	if entries, err := os.ReadDir(outputDir); err == nil {
+		for _, e := range entries {
+			if e.IsDir() && strings.HasPrefix(e.Name(), jobWorkPrefix) {
+				removeLogged(filepath.Join(outputDir, e.Name()), "orphaned job workspace")
+			}
+		}
+	}
+
+	thumbs := map[string]bool{}
+
+	if items, err := listLibrary(); err == nil {
+		for _, it := range items {
+			thumbs[filepath.Base(libraryThumbnailPath(it.Name, it.info))] = true
+		}
+	}
+
+	if entries, err := os.ReadDir(uploadDir); err == nil {
+		for _, e := range entries {
+			name := e.Name()
+			if thumbs[name] || isResumableTusFile(name) {
+				continue
+			}
+
+			removeLogged(filepath.Join(uploadDir, name), "orphaned temp file")
+		}
+	}
+
+	// 参考片段上传中断时残留的临时文件
+	if entries, err := os.ReadDir(referenceDir); err == nil {
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ".upload_") {
+				removeLogged(filepath.Join(referenceDir, e.Name()), "orphaned temp file")
+			}
+		}
+	}
+}
+
+// isResumableTusFile 判断是否为数据和信息文件都存在的断点续传上传文件
+func isResumableTusFile(name string) bool {
+	id, ok := tusFileID(name)
+	if !ok {
+		return false
+	}
+
+	_, errData := os.Stat(tusDataPath(id))
+	_, errInfo := os.Stat(tusInfoPath(id))
+
+	return errData == nil && errInfo == nil
+}
+
+// tusFileID 从 tus_<id>.part 或 tus_<id>.json 中取出上传 ID
+func tusFileID(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, "tus_")
+	if !ok {
+		return "", false
+	}
+
+	id := strings.TrimSuffix(strings.TrimSuffix(rest, ".part"), ".json")
+
+	return id, tusIDPattern.MatchString(id) && id != rest
+}
+
+// startJanitor 启动后台定期清理: 移除已结束的过期任务和未提交的过期暂存文件, 配置了保留时间或输出目录大小上限时同时清理文件
+func startJanitor() {
+	go func() {
+		ticker := time.NewTicker(time.Duration(retentionCheckMinutes) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			evictJobs()
+			expireStaged()
+
+			if retentionMaxAgeHours > 0 || retentionMaxOutputSize > 0 {
+				sweepRetention()
+			}
+
+			<-ticker.C
+		}
+	}()
+}
+
+// evictJobs 移除结束超过 job_ttl_minutes 的任务, 避免内存随运行时间增长
+func evictJobs() {
+	cutoff := time.Now().Add(-time.Duration(jobTTLMinutes) * time.Minute)
+
+	if n := jobs.EvictFinished(cutoff); n > 0 {
+		log.Printf("janitor: evicted %d finished jobs", n)
+	}
+}
+
+// expireStaged 删除超过 staged_ttl_minutes(或更短的 retention_max_age_hours)仍未提交的暂存文件及其缩略图
+func expireStaged() {
+	ttl := time.Duration(stagedTTLMinutes) * time.Minute
+	if retentionMaxAgeHours > 0 {
+		ttl = min(ttl, time.Duration(retentionMaxAgeHours)*time.Hour)
+	}
+
+	for _, f := range staging.TakeExpired(time.Now().Add(-ttl)) {
+		f.removeSprite()
+		removeLogged(f.path, "staged file expired")
+	}
+}
+
+// sweepRetention 执行一次清理: 超时的断点续传上传和输出文件, 以及超出大小上限的最早输出
+func sweepRetention() {
+	now := time.Now()
+
+	if retentionMaxAgeHours > 0 {
+		expireTusUploads(now.Add(-time.Duration(retentionMaxAgeHours) * time.Hour))
+	}
+
+	items, err := listLibrary()
+	if err != nil {
+		log.Printf("retention: list outputs error: %v", err)
+		return
+	}
+
+	// 输出文件的修改时间是拍摄时间, 按文件写入输出目录的时间判断新旧
+	added := make(map[string]time.Time, len(items))
+	for _, it := range items {
+		added[it.Name] = fileAddedTime(it.path, it.info)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return added[items[i].Name].Before(added[items[j].Name])
+	})
+
+	kept := items[:0]
+
+	for _, it := range items {
+		if retentionMaxAgeHours > 0 && now.Sub(added[it.Name]) > time.Duration(retentionMaxAgeHours)*time.Hour {
+			if expireOutput(it.Name, "max age exceeded") {
+				continue
+			}
+		}
+
+		kept = append(kept, it)
+	}
+
+	if retentionMaxOutputSize <= 0 {
+		return
+	}
+
+	var total int64
+	for _, it := range kept {
+		total += it.Size
+	}
+
+	for _, it := range kept {
+		if total <= retentionMaxOutputSize {
+			break
+		}
+
+		if expireOutput(it.Name, "output dir over size limit") {
+			total -= it.Size
+		}
+	}
+}
+
+// expireTusUploads 删除在 cutoff 之后没有再上传数据的断点续传上传
+func expireTusUploads(cutoff time.Time) {
+	entries, err := os.ReadDir(uploadDir)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+
+		id, ok := tusFileID(e.Name())
+		if !ok {
+			continue
+		}
+
+		unlock := lockTus(id)
+
+		if info, err := os.Stat(tusDataPath(id)); err != nil || info.ModTime().Before(cutoff) {
+			removeTusUpload(id)
+			log.Printf("retention: removed tus upload %s (max age exceeded)", id)
+		}
+
+		unlock()
+	}
+}
+
+// countingResponseWriter 记录响应状态码和已写入的字节数, 用于判断下载是否完整
+type countingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+// WriteHeader 记录状态码
+func (c *countingResponseWriter) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+	}
+
+	c.ResponseWriter.WriteHeader(code)
+}
+
+// Write 记录写入的字节数
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	n, err := c.ResponseWriter.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// Unwrap 供 http.ResponseController 访问原始 ResponseWriter
+func (c *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
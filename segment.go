@@ -0,0 +1,313 @@
+//
+// FilePath    : video-trim\segment.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 多段裁剪: 区间解析、规范化, 以及分段导出后无损拼接
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 区间列表的含义
+const (
+	cutModeRemove = "remove" // 删除列出的区间, 保留其余部分
+	cutModeKeep   = "keep"   // 只保留列出的区间
+)
+
+// minSegment 短于该时长的片段直接丢弃, 避免产生无意义的碎片
+const minSegment = 10 * time.Millisecond
+
+// timeRange 媒体中的一个时间区间 [Start, End)
+// 由 planSegments 生成的片段中 End 为 0 表示一直到文件末尾(时长未知时)
+type timeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// MarshalJSON 以秒为单位输出区间
+func (t timeRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+	}{
+		Start: t.Start.Seconds(),
+		End:   t.End.Seconds(),
+	})
+}
+
+// Len 返回区间长度, 开放区间返回 0
+func (t timeRange) Len() time.Duration {
+	if t.End <= t.Start {
+		return 0
+	}
+
+	return t.End - t.Start
+}
+
+// parseTimeRanges 解析区间列表, 例如 "1:00-1:30, 5:10-5:40.5"
+// 区间之间可用逗号、分号或换行分隔, 每个区间的结束时间必须大于开始时间
+func parseTimeRanges(s string) ([]timeRange, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r' || r == '，' || r == '；'
+	})
+
+	ranges := []timeRange{}
+
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+
+		startStr, endStr, ok := strings.Cut(f, "-")
+		if !ok {
+			return nil, &trimValueError{Field: "cuts", Value: f}
+		}
+
+		start, err := parseTimecode(startStr)
+		if err != nil {
+			return nil, &trimValueError{Field: "cuts", Value: f}
+		}
+
+		end, err := parseTimecode(endStr)
+		if err != nil || end <= start {
+			return nil, &trimValueError{Field: "cuts", Value: f}
+		}
+
+		ranges = append(ranges, timeRange{Start: start, End: end})
+	}
+
+	return ranges, nil
+}
+
+// mergeRanges 排序并合并重叠或相邻的区间
+func mergeRanges(ranges []timeRange) []timeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sorted := append([]timeRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := []timeRange{sorted[0]}
+
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+
+			continue
+		}
+
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// planSegments 根据掐头去尾和区间列表计算需要保留的片段
+// duration 为媒体时长, 未知时为 0(此时不允许去尾和区间列表)
+func planSegments(opts trimOptions, duration time.Duration) ([]timeRange, error) {
+	if duration <= 0 {
+		if opts.Tail > 0 || len(opts.Cuts) > 0 {
+			return nil, fmt.Errorf("invalid media duration: %v", duration)
+		}
+
+		return []timeRange{{Start: opts.Head}}, nil
+	}
+
+	// 掐头去尾后的基础区间
+	base := timeRange{Start: opts.Head, End: duration - opts.Tail}
+	if base.End <= base.Start {
+		return nil, fmt.Errorf("head + tail exceeds media duration")
+	}
+
+	// 将区间裁剪到基础区间内, 超出范围的部分被忽略
+	cuts := []timeRange{}
+
+	for _, c := range mergeRanges(opts.Cuts) {
+		c.Start = max(c.Start, base.Start)
+		c.End = min(c.End, base.End)
+
+		if c.End > c.Start {
+			cuts = append(cuts, c)
+		}
+	}
+
+	var kept []timeRange
+
+	if opts.CutMode == cutModeKeep && len(opts.Cuts) > 0 {
+		kept = cuts
+	} else {
+		kept = subtractRanges(base, cuts)
+	}
+
+	// 丢弃过短的碎片
+	segments := []timeRange{}
+
+	for _, k := range kept {
+		if k.Len() >= minSegment {
+			segments = append(segments, k)
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("nothing left to keep after applying cuts")
+	}
+
+	return segments, nil
+}
+
+// subtractRanges 从 base 中去掉已排序且不重叠的 cuts, 返回剩余区间
+func subtractRanges(base timeRange, cuts []timeRange) []timeRange {
+	kept := []timeRange{}
+	cursor := base.Start
+
+	for _, c := range cuts {
+		if c.Start > cursor {
+			kept = append(kept, timeRange{Start: cursor, End: c.Start})
+		}
+
+		cursor = max(cursor, c.End)
+	}
+
+	if base.End > cursor {
+		kept = append(kept, timeRange{Start: cursor, End: base.End})
+	}
+
+	return kept
+}
+
+// totalLength 返回所有片段的总时长, 存在开放片段时返回 0
+func totalLength(segments []timeRange) time.Duration {
+	var total time.Duration
+
+	for _, s := range segments {
+		if s.End == 0 {
+			return 0
+		}
+
+		total += s.Len()
+	}
+
+	return total
+}
+
+// runSegments 逐段导出保留的片段(流复制或重新编码边界部分), 再通过 concat demuxer 无损拼接为一个文件
+// 分段文件不保留全局元数据, 拼接时从原视频复制并按 md 恢复旋转角度, metaArgs 为输出元数据参数
+func runSegments(ctx context.Context, ffmpegPath, absInput, absOutput string, pieces []renderPiece, streamArgs []string, md mediaMetadata, metaArgs []string, onProgress progressFunc) error {
+	ext := filepath.Ext(absOutput)
+	base := strings.TrimSuffix(absOutput, ext)
+
+	segments := make([]timeRange, 0, len(pieces))
+	for _, p := range pieces {
+		segments = append(segments, p.timeRange)
+	}
+
+	total := totalLength(segments)
+
+	// 重新编码部分的参数集(SPS/PPS)与原视频不同, 使用 MPEG-TS 中间文件让每段都携带自己的参数集, 拼接后才能正确解码
+	partExt := ext
+
+	ts := hasEncodedPiece(pieces)
+	if ts {
+		partExt = ".ts"
+	}
+
+	parts := make([]string, 0, len(pieces))
+
+	// 无论成功与否都清理分段临时文件
+	defer func() {
+		for _, p := range parts {
+			os.Remove(p)
+		}
+	}()
+
+	var done time.Duration
+
+	for i, p := range pieces {
+		seg := p.timeRange
+		part := fmt.Sprintf("%s.part%d%s", base, i, partExt)
+		parts = append(parts, part)
+
+		// 将单段进度换算为整体进度
+		offset := done
+		segProgress := func(p ffmpegProgress) {
+			if onProgress == nil || total <= 0 {
+				return
+			}
+
+			elapsed := offset + time.Duration(p.Percent/100*float64(seg.Len()))
+			overall := calcProgress(elapsed.Seconds(), p.Speed, total.Seconds())
+			onProgress(overall)
+		}
+
+		args := buildPieceArgs(absInput, part, p, streamArgs, ts)
+		if err := runFFmpegArgs(ctx, ffmpegPath, args, seg.Len(), segProgress); err != nil {
+			return fmt.Errorf("segment %d: %w", i, err)
+		}
+
+		done += seg.Len()
+	}
+
+	// 生成 concat 列表文件
+	listPath := base + ".concat.txt"
+	if err := writeConcatList(listPath, parts); err != nil {
+		return err
+	}
+	defer os.Remove(listPath)
+
+	// 流复制的分段文件保留了 display matrix, 只有 MPEG-TS 中间文件需要重新指定旋转角度
+	var inArgs []string
+	if ts {
+		inArgs = rotationArgs(md)
+	}
+
+	return runFFmpegArgs(ctx, ffmpegPath, buildConcatArgs(listPath, absInput, absOutput, inArgs, metaArgs), 0, onProgress)
+}
+
+// writeConcatList 写出 ffmpeg concat demuxer 使用的列表文件
+func writeConcatList(listPath string, parts []string) error {
+	var sb strings.Builder
+
+	for _, p := range parts {
+		// concat 列表中的单引号需要转义为 '\''
+		fmt.Fprintf(&sb, "file '%s'\n", strings.ReplaceAll(filepath.ToSlash(p), "'", `'\''`))
+	}
+
+	return os.WriteFile(listPath, []byte(sb.String()), 0600)
+}
+
+// buildConcatArgs 构建使用 concat demuxer 无损拼接的 ffmpeg 参数, 保留分段文件中的全部流
+// 原视频作为第二个输入, 只用于提供元数据; inArgs 为 concat 输入的参数, metaArgs 为输出元数据参数
+func buildConcatArgs(listPath, absInput, absOutput string, inArgs, metaArgs []string) []string {
+	args := []string{"-f", "concat", "-safe", "0"}
+	args = append(args, inArgs...)
+	args = append(args,
+		"-i", listPath,
+		"-i", absInput,
+		"-map", "0",
+		"-map_metadata", "1",
+		"-map_chapters", "0",
+		"-c", "copy",
+	)
+	args = append(args, metaArgs...)
+
+	return append(args,
+		"-avoid_negative_ts", "make_zero",
+		absOutput,
+	)
+}
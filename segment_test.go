@@ -0,0 +1,164 @@
+//
+// FilePath    : video-trim\segment_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 区间列表解析和片段规划的测试
+//
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimeRanges(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []timeRange
+		wantErr bool
+	}{
+		{in: "", want: []timeRange{}},
+		{in: "1:00-1:30, 5:10-5:40.5", want: []timeRange{{secs(60), secs(90)}, {secs(310), secs(340.5)}}},
+		{in: "1-2;3-4\n5-6", want: []timeRange{{secs(1), secs(2)}, {secs(3), secs(4)}, {secs(5), secs(6)}}},
+		{in: "1-2，3-4；", want: []timeRange{{secs(1), secs(2)}, {secs(3), secs(4)}}},
+		{in: " 10 - 20 ", want: []timeRange{{secs(10), secs(20)}}},
+		{in: "5-3", wantErr: true},
+		{in: "5-5", wantErr: true},
+		{in: "1:00", wantErr: true},
+		{in: "a-b", wantErr: true},
+		{in: "1-2, 3-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimeRanges(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTimeRanges(%q) = %v, want error", tt.in, got)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTimeRanges(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []timeRange
+		want []timeRange
+	}{
+		{name: "empty", in: nil, want: nil},
+		{name: "disjoint", in: []timeRange{{secs(1), secs(2)}, {secs(3), secs(4)}}, want: []timeRange{{secs(1), secs(2)}, {secs(3), secs(4)}}},
+		{name: "unsorted", in: []timeRange{{secs(3), secs(4)}, {secs(1), secs(2)}}, want: []timeRange{{secs(1), secs(2)}, {secs(3), secs(4)}}},
+		{name: "overlapping", in: []timeRange{{secs(1), secs(5)}, {secs(3), secs(8)}}, want: []timeRange{{secs(1), secs(8)}}},
+		{name: "adjacent", in: []timeRange{{secs(1), secs(2)}, {secs(2), secs(3)}}, want: []timeRange{{secs(1), secs(3)}}},
+		{name: "contained", in: []timeRange{{secs(1), secs(10)}, {secs(2), secs(3)}}, want: []timeRange{{secs(1), secs(10)}}},
+	}
+
+	for _, tt := range tests {
+		if got := mergeRanges(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: mergeRanges() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSegments(t *testing.T) {
+	remove := func(head, tail time.Duration, cuts ...timeRange) trimOptions {
+		return trimOptions{Head: head, Tail: tail, Cuts: cuts, CutMode: cutModeRemove}
+	}
+
+	keep := func(cuts ...timeRange) trimOptions {
+		return trimOptions{Cuts: cuts, CutMode: cutModeKeep}
+	}
+
+	tests := []struct {
+		name     string
+		opts     trimOptions
+		duration time.Duration
+		want     []timeRange
+		wantErr  bool
+	}{
+		{
+			name:     "head and tail",
+			opts:     remove(secs(6), secs(4)),
+			duration: secs(100),
+			want:     []timeRange{{secs(6), secs(96)}},
+		},
+		{
+			name:     "remove overlapping cuts",
+			opts:     remove(0, 0, timeRange{secs(10), secs(20)}, timeRange{secs(15), secs(30)}, timeRange{secs(50), secs(60)}),
+			duration: secs(100),
+			want:     []timeRange{{0, secs(10)}, {secs(30), secs(50)}, {secs(60), secs(100)}},
+		},
+		{
+			name:     "cuts clipped to head and tail",
+			opts:     remove(secs(5), secs(10), timeRange{0, secs(8)}, timeRange{secs(85), secs(120)}),
+			duration: secs(100),
+			want:     []timeRange{{secs(8), secs(85)}},
+		},
+		{
+			name:     "keep ranges",
+			opts:     keep(timeRange{secs(90), secs(120)}, timeRange{secs(10), secs(20)}),
+			duration: secs(100),
+			want:     []timeRange{{secs(10), secs(20)}, {secs(90), secs(100)}},
+		},
+		{
+			name:     "short fragment dropped",
+			opts:     remove(0, 0, timeRange{secs(10), secs(99.995)}),
+			duration: secs(100),
+			want:     []timeRange{{0, secs(10)}},
+		},
+		{
+			name:     "unknown duration keeps open segment",
+			opts:     remove(secs(5), 0),
+			duration: 0,
+			want:     []timeRange{{Start: secs(5)}},
+		},
+		{
+			name:     "unknown duration with tail",
+			opts:     remove(0, secs(1)),
+			duration: 0,
+			wantErr:  true,
+		},
+		{
+			name:     "head and tail exceed duration",
+			opts:     remove(secs(60), secs(50)),
+			duration: secs(100),
+			wantErr:  true,
+		},
+		{
+			name:     "everything removed",
+			opts:     remove(0, 0, timeRange{0, secs(100)}),
+			duration: secs(100),
+			wantErr:  true,
+		},
+		{
+			name:     "keep ranges outside media",
+			opts:     keep(timeRange{secs(200), secs(300)}),
+			duration: secs(100),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := planSegments(tt.opts, tt.duration)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: planSegments() = %v, want error", tt.name, got)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: planSegments() = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
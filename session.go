@@ -0,0 +1,343 @@
+//
+// FilePath    : video-trim\session.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 会话: 每个浏览器使用签名 Cookie 标识, 任务、暂存文件、上传和输出文件归属于创建它们的会话
+//
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 会话 Cookie 名称和有效期
+const (
+	sessionCookieName = "vt_session"
+	adminCookieName   = "vt_admin"
+	sessionMaxAge     = 365 * 24 * time.Hour
+)
+
+// 状态目录中保存会话状态的文件, 状态目录不在输出目录内, 不会被下载或清理
+const (
+	sessionSecretFile = ".session_secret"
+	outputOwnersFile  = ".owners.json"
+	pairedDevicesFile = ".devices.json"
+)
+
+// sessionKey 会话 Cookie 的签名密钥, 由 initSessions 初始化
+var sessionKey []byte
+
+// sessionContextKey 请求上下文中保存会话 ID 的键
+type sessionContextKey struct{}
+
+// initSessions 初始化签名密钥并读取输出文件的归属
+// 未配置 session_secret 时使用状态目录中保存的随机密钥, 首次运行时生成, 重启后会话仍然有效
+func initSessions() {
+	if sessionSecret != "" {
+		sum := sha256.Sum256([]byte(sessionSecret))
+		sessionKey = sum[:]
+	} else {
+		sessionKey = loadOrCreateSessionKey(filepath.Join(stateDir, sessionSecretFile))
+	}
+
+	outputOwners.load()
+	pairedDevices.load()
+}
+
+// loadOrCreateSessionKey 读取保存的密钥, 不存在或无效时生成新的密钥并保存
+func loadOrCreateSessionKey(path string) []byte {
+	if b, err := os.ReadFile(path); err == nil {
+		if key, err := hex.DecodeString(strings.TrimSpace(string(b))); err == nil && len(key) == 32 {
+			return key
+		}
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("generate session key error: %v", err)
+	}
+
+	// 保存失败时密钥只在本次运行有效, 重启后需要重新建立会话
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		log.Printf("save session key error: %v", err)
+	}
+
+	return key
+}
+
+// signSession 返回会话 Cookie 的值: <会话 ID>.<签名>; 已配对设备的会话 Cookie 还带有设备 ID: <会话 ID>.<设备 ID>.<签名>
+func signSession(id, device string) string {
+	payload := id
+	if device != "" {
+		payload += "." + device
+	}
+
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte(payload))
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySession 校验会话 Cookie 的签名, 返回会话 ID 和配对设备 ID(普通会话为空)
+func verifySession(value string) (string, string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", "", false
+	}
+
+	id, device, _ := strings.Cut(value[:i], ".")
+	if len(id) != 32 {
+		return "", "", false
+	}
+
+	if !hmac.Equal([]byte(signSession(id, device)), []byte(value)) {
+		return "", "", false
+	}
+
+	return id, device, true
+}
+
+// newSessionID 生成随机的会话 ID
+func newSessionID() string {
+	return newJobID() + newJobID()
+}
+
+// withSession 为每个请求确定会话: Cookie 无效或不存在时创建新会话并下发 Cookie
+func withSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id string
+
+		if c, err := r.Cookie(sessionCookieName); err == nil {
+			var device string
+			id, device, _ = verifySession(c.Value)
+
+			// 配对时下发的会话 Cookie 与设备绑定, 设备撤销后随之失效
+			if device != "" {
+				if _, ok := pairedDevices.Get(device); !ok {
+					id = ""
+				}
+			}
+		}
+
+		// 已配对的设备在会话 Cookie 过期后恢复配对时的会话
+		if d, ok := deviceFromRequest(r); ok {
+			pairedDevices.Touch(d.ID, clientIP(r))
+
+			if id == "" {
+				id = d.Session
+				setSessionCookie(w, r, id, d.ID)
+			}
+		}
+
+		if id == "" {
+			id = newSessionID()
+			setSessionCookie(w, r, id, "")
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, id)))
+	})
+}
+
+// setSessionCookie 下发会话 Cookie, device 为绑定的配对设备 ID(普通会话为空)
+func setSessionCookie(w http.ResponseWriter, r *http.Request, id, device string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    signSession(id, device),
+		Path:     "/",
+		MaxAge:   int(sessionMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// requestSession 返回请求所属的会话 ID
+func requestSession(r *http.Request) string {
+	id, _ := r.Context().Value(sessionContextKey{}).(string)
+
+	return id
+}
+
+// isAdmin 判断请求是否来自管理员: 请求头 X-Admin-Token 为管理员令牌, 或者浏览器已在管理页面输入过管理员令牌
+func isAdmin(r *http.Request) bool {
+	if adminToken == "" {
+		return false
+	}
+
+	if token := r.Header.Get("X-Admin-Token"); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+	}
+
+	c, err := r.Cookie(adminCookieName)
+
+	return err == nil && hmac.Equal([]byte(c.Value), []byte(signAdmin(requestSession(r))))
+}
+
+// signAdmin 返回管理员 Cookie 的值, 与会话绑定, 修改管理员令牌后失效
+func signAdmin(session string) string {
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte("admin|" + session + "|" + adminToken))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// canAccess 判断请求能否访问属于 owner 的任务或文件: 对本会话可见, 或者请求来自管理员
+func canAccess(r *http.Request, owner string) bool {
+	return isAdmin(r) || visibleTo(owner, requestSession(r))
+}
+
+// visibleTo 判断属于 owner 的文件对会话 session 是否可见: 本会话创建的;
+// 没有归属的文件(如升级前生成的输出)在未配置管理员令牌时所有会话可见, 配置后只有管理员可以访问
+func visibleTo(owner, session string) bool {
+	if owner == "" {
+		return adminToken == ""
+	}
+
+	return owner == session
+}
+
+// ownerStore 输出文件的归属会话, 保存在状态目录中, 重启后仍然有效
+type ownerStore struct {
+	mu     sync.Mutex
+	owners map[string]string // 输出文件名 -> 会话 ID
+}
+
+// outputOwners 全局输出文件归属表
+var outputOwners = &ownerStore{owners: map[string]string{}}
+
+// load 读取归属表, 并去掉已不存在的文件
+func (s *ownerStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(filepath.Join(stateDir, outputOwnersFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("read output owners error: %v", err)
+		}
+
+		return
+	}
+
+	owners := map[string]string{}
+	if err := json.Unmarshal(b, &owners); err != nil {
+		log.Printf("parse output owners error: %v", err)
+		return
+	}
+
+	for name := range owners {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			delete(owners, name)
+		}
+	}
+
+	s.owners = owners
+	s.save()
+}
+
+// save 保存归属表, 调用方需持有 s.mu
+func (s *ownerStore) save() {
+	if err := saveJSONFile(filepath.Join(stateDir, outputOwnersFile), s.owners); err != nil {
+		log.Printf("save output owners error: %v", err)
+	}
+}
+
+// saveJSONFile 将 v 写入临时文件后替换 path, 写入中断时不会留下不完整的文件
+func saveJSONFile(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+".tmp", b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// Owner 返回输出文件所属的会话 ID, 没有归属时返回空字符串
+func (s *ownerStore) Owner(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.owners[name]
+}
+
+// Set 登记输出文件的归属
+func (s *ownerStore) Set(name, owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.owners[name] = owner
+	s.save()
+}
+
+// Rename 输出文件重命名后同步归属, newName 为空表示已删除
+func (s *ownerStore) Rename(oldName, newName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.owners[oldName]
+	if !ok {
+		return
+	}
+
+	delete(s.owners, oldName)
+
+	if newName != "" {
+		s.owners[newName] = owner
+	}
+
+	s.save()
+}
+
+// Reset 清空归属表(管理员清理全部文件后)
+func (s *ownerStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.owners = map[string]string{}
+	s.save()
+}
+
+// clearSessionFiles 删除会话生成的输出文件、暂存文件和断点续传上传, 处理中的任务不受影响
+// 没有归属的文件即使对会话可见也不删除, 只能由管理员清理全部文件时删除
+func clearSessionFiles(owner string) {
+	if owner == "" {
+		return
+	}
+
+	if items, err := listLibrary(); err == nil {
+		for _, it := range items {
+			if outputOwners.Owner(it.Name) == owner {
+				if err := removeOutput(it.Name); err != nil && !os.IsNotExist(err) {
+					log.Printf("clear output %s error: %v", it.Name, err)
+				}
+			}
+		}
+	}
+
+	for _, f := range staging.TakeOwned(owner) {
+		f.removeSprite()
+		os.Remove(f.path)
+	}
+
+	removeTusUploads(func(u *tusUpload) bool { return u.Owner == owner })
+}
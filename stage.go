@@ -0,0 +1,461 @@
+//
+// FilePath    : video-trim\stage.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 暂存上传: 先上传文件生成时间轴缩略图(雪碧图 + WebVTT), 在页面上选择入点/出点后再提交裁剪
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 缩略图尺寸(像素), 雪碧图每行 spriteColumns 张
+const (
+	thumbWidth    = 160
+	thumbHeight   = 90
+	spriteColumns = 10
+)
+
+// stagedFile 已暂存在服务器上、等待提交裁剪的文件
+type stagedFile struct {
+	ID        string    `json:"id"`         // 暂存 ID
+	Name      string    `json:"name"`       // 上传时的原始文件名
+	Size      int64     `json:"size"`       // 文件大小(字节)
+	Duration  float64   `json:"duration"`   // 媒体时长(秒), 未知时为 0
+	CreatedAt time.Time `json:"created_at"` // 暂存时间
+
+	path    string    // 暂存文件路径
+	modTime time.Time // 浏览器提供的文件修改时间
+	owner   string    // 暂存文件的会话 ID
+
+	mu        sync.Mutex         // 保护以下缩略图字段, 生成雪碧图时不持有
+	sprite    *spriteSheet       // 已生成的雪碧图, 未生成时为 nil
+	rendering chan struct{}      // 正在生成雪碧图时不为 nil, 生成结束后关闭
+	cancel    context.CancelFunc // 取消正在进行的生成
+	removed   bool               // 文件已提交或删除, 不再生成雪碧图
+}
+
+// spriteSheet 时间轴缩略图雪碧图
+type spriteSheet struct {
+	Path     string        // 雪碧图文件路径
+	Interval time.Duration // 相邻缩略图的时间间隔
+	Count    int           // 缩略图数量
+}
+
+// stageStore 暂存文件表
+type stageStore struct {
+	mu    sync.Mutex
+	files map[string]*stagedFile
+}
+
+// staging 全局暂存文件表
+var staging = &stageStore{files: map[string]*stagedFile{}}
+
+// Add 登记暂存文件
+func (s *stageStore) Add(f *stagedFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[f.ID] = f
+}
+
+// Get 根据 ID 查找暂存文件
+func (s *stageStore) Get(id string) (*stagedFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[id]
+
+	return f, ok
+}
+
+// Take 取出暂存文件, 取出后不能再次使用
+func (s *stageStore) Take(id string) (*stagedFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[id]
+	delete(s.files, id)
+
+	return f, ok
+}
+
+// Restore 放回取出后未能使用的暂存文件
+func (s *stageStore) Restore(files []*stagedFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range files {
+		s.files[f.ID] = f
+	}
+}
+
+// TakeOwned 取出属于会话 owner 的全部暂存文件
+func (s *stageStore) TakeOwned(owner string) []*stagedFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var owned []*stagedFile
+
+	for id, f := range s.files {
+		if f.owner == owner {
+			owned = append(owned, f)
+			delete(s.files, id)
+		}
+	}
+
+	return owned
+}
+
+// TakeAll 取出全部暂存文件
+func (s *stageStore) TakeAll() []*stagedFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make([]*stagedFile, 0, len(s.files))
+	for _, f := range s.files {
+		all = append(all, f)
+	}
+
+	s.files = map[string]*stagedFile{}
+
+	return all
+}
+
+// TakeExpired 取出暂存时间早于 before 的文件
+func (s *stageStore) TakeExpired(before time.Time) []*stagedFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*stagedFile
+
+	for id, f := range s.files {
+		if f.CreatedAt.Before(before) {
+			expired = append(expired, f)
+			delete(s.files, id)
+		}
+	}
+
+	return expired
+}
+
+// removeSprite 删除已生成的雪碧图, 并取消正在进行的生成; 文件已提交或删除后调用, 之后不再生成
+func (f *stagedFile) removeSprite() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.removed = true
+
+	if f.cancel != nil {
+		f.cancel()
+	}
+
+	if f.sprite != nil {
+		os.Remove(f.sprite.Path)
+		f.sprite = nil
+	}
+}
+
+// ensureSprite 返回文件的雪碧图, 首次调用时生成; 同时请求时等待正在进行的生成
+// 生成 ffmpeg 雪碧图耗时较长, 期间不持有 f.mu, 提交或删除文件时不必等待
+func (f *stagedFile) ensureSprite(ctx context.Context) (*spriteSheet, error) {
+	for {
+		f.mu.Lock()
+
+		if sheet := f.sprite; sheet != nil {
+			f.mu.Unlock()
+			return sheet, nil
+		}
+
+		if f.removed {
+			f.mu.Unlock()
+			return nil, fmt.Errorf("staged file removed")
+		}
+
+		if f.Duration <= 0 {
+			f.mu.Unlock()
+			return nil, fmt.Errorf("unknown media duration")
+		}
+
+		// 等待其他请求的生成结束; 该请求中途取消时重新检查, 必要时由本请求生成
+		if done := f.rendering; done != nil {
+			f.mu.Unlock()
+
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		break
+	}
+
+	renderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	f.rendering, f.cancel = done, cancel
+	f.mu.Unlock()
+
+	sheet, err := generateSprite(renderCtx, f.path, time.Duration(f.Duration*float64(time.Second)))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rendering, f.cancel = nil, nil
+	close(done)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成期间文件已提交或删除
+	if f.removed {
+		os.Remove(sheet.Path)
+		return nil, fmt.Errorf("staged file removed")
+	}
+
+	f.sprite = sheet
+
+	return sheet, nil
+}
+
+// generateSprite 每隔固定时间截取一张缩略图并拼接为雪碧图
+// 缩略图数量超过 thumbnailMaxCount 时自动加大间隔; 只解码关键帧以加快速度
+func generateSprite(ctx context.Context, input string, duration time.Duration) (*spriteSheet, error) {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+
+	interval := time.Duration(thumbnailIntervalSeconds) * time.Second
+	if maxCount := time.Duration(thumbnailMaxCount); duration/interval > maxCount {
+		interval = (duration + maxCount - 1) / maxCount
+	}
+
+	count := int(math.Ceil(float64(duration) / float64(interval)))
+	rows := (count + spriteColumns - 1) / spriteColumns
+
+	filter := fmt.Sprintf(
+		"fps=1/%s,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		formatFFmpegTime(interval), thumbWidth, thumbHeight, thumbWidth, thumbHeight, spriteColumns, rows,
+	)
+
+	out := strings.TrimSuffix(input, filepath.Ext(input)) + ".sprite.jpg"
+
+	cmd := newCommand(ctx, ffmpegPath,
+		"-nostdin", "-v", "error",
+		"-skip_frame", "nokey",
+		"-i", input,
+		"-map", "0:v:0",
+		"-an", "-sn", "-dn",
+		"-vf", filter,
+		"-frames:v", "1",
+		"-q:v", "5",
+		"-y", out,
+	)
+
+	if b, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(out)
+		return nil, fmt.Errorf("generate sprite: %w: %s", err, strings.TrimSpace(string(b)))
+	}
+
+	return &spriteSheet{Path: out, Interval: interval, Count: count}, nil
+}
+
+// buildThumbnailVTT 生成 WebVTT 缩略图轨道, 每条 cue 指向雪碧图中的一块区域(#xywh=)
+func buildThumbnailVTT(spriteURL string, sheet *spriteSheet, duration time.Duration) string {
+	var sb strings.Builder
+
+	sb.WriteString("WEBVTT\n\n")
+
+	for i := 0; i < sheet.Count; i++ {
+		start := time.Duration(i) * sheet.Interval
+		end := min(start+sheet.Interval, duration)
+
+		x := (i % spriteColumns) * thumbWidth
+		y := (i / spriteColumns) * thumbHeight
+
+		fmt.Fprintf(&sb, "%s --> %s\n%s#xywh=%d,%d,%d,%d\n\n",
+			formatVTTTime(start), formatVTTTime(end), spriteURL, x, y, thumbWidth, thumbHeight)
+	}
+
+	return sb.String()
+}
+
+// formatVTTTime 将时长格式化为 WebVTT 时间戳 HH:MM:SS.mmm
+func formatVTTTime(d time.Duration) string {
+	ms := d.Milliseconds()
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// stagedTrim 从表单中读取暂存文件的入点/出点(字段 in_<id>、out_<id>), 换算为该文件的掐头/去尾时长
+// 字段不存在时返回 nil, 表示使用任务的统一参数
+func stagedTrim(r *http.Request, f *stagedFile) (*float64, *float64, error) {
+	var head, tail *float64
+
+	duration := time.Duration(f.Duration * float64(time.Second))
+
+	var in time.Duration
+
+	if s := strings.TrimSpace(r.FormValue("in_" + f.ID)); s != "" {
+		d, err := parseTimecode(s)
+		if err != nil {
+			return nil, nil, &trimValueError{Field: "in", Value: s}
+		}
+
+		in = d
+		v := d.Seconds()
+		head = &v
+	}
+
+	if s := strings.TrimSpace(r.FormValue("out_" + f.ID)); s != "" {
+		out, err := parseTimecode(s)
+		if err != nil || out <= in || duration <= 0 {
+			return nil, nil, &trimValueError{Field: "out", Value: s}
+		}
+
+		v := max(duration-out, 0).Seconds()
+		tail = &v
+	}
+
+	return head, tail, nil
+}
+
+// handleStage 暂存上传的视频并返回其信息(JSON), 之后可获取缩略图并提交裁剪
+func handleStage(w http.ResponseWriter, r *http.Request) {
+	// 接收前确认磁盘空间足够保存暂存文件和之后的输出文件
+	if !checkDiskSpaceOrRespond(w, r, uploadSpaceFactor*r.ContentLength) {
+		return
+	}
+
+	// 文件直接写入暂存路径, 每个文件使用独立的暂存 ID
+	var ids []string
+
+	form, ok := readUploadFormOrRespond(w, r, "videos", func(_ int, filename string) string {
+		id := newJobID()
+		ids = append(ids, id)
+
+		return filepath.Join(uploadDir, "staged_"+id+uploadExt(filename))
+	})
+	if !ok {
+		return
+	}
+
+	if len(form.Files) == 0 {
+		respondSelectAtLeastOne(w, r)
+		return
+	}
+
+	staged := make([]*stagedFile, 0, len(form.Files))
+	modTimes := r.PostForm["last_modified"]
+
+	for idx, u := range form.Files {
+		duration, _ := probeDurationForTrim(r.Context(), u.Path, false)
+
+		f := &stagedFile{
+			ID:        ids[idx],
+			Name:      u.Filename,
+			Size:      u.Size,
+			Duration:  duration.Seconds(),
+			CreatedAt: time.Now(),
+			path:      u.Path,
+			owner:     requestSession(r),
+		}
+
+		if idx < len(modTimes) {
+			f.modTime = parseLastModified(modTimes[idx])
+		}
+
+		staging.Add(f)
+		staged = append(staged, f)
+	}
+
+	writeJSON(w, http.StatusCreated, staged)
+}
+
+// getStagedOrRespond 根据路径中的 ID 查找暂存文件, 不存在或不属于本会话时响应 404
+func getStagedOrRespond(w http.ResponseWriter, r *http.Request) (*stagedFile, bool) {
+	f, ok := staging.Get(r.PathValue("id"))
+	if !ok || !canAccess(r, f.owner) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	return f, true
+}
+
+// handleStageSprite 返回暂存文件的缩略图雪碧图
+func handleStageSprite(w http.ResponseWriter, r *http.Request) {
+	f, ok := getStagedOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	sheet, err := f.ensureSprite(r.Context())
+	if err != nil {
+		log.Printf("sprite for %s error: %v", f.Name, err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeFile(w, r, sheet.Path)
+}
+
+// handleStageThumbnails 返回暂存文件的 WebVTT 缩略图轨道
+func handleStageThumbnails(w http.ResponseWriter, r *http.Request) {
+	f, ok := getStagedOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	sheet, err := f.ensureSprite(r.Context())
+	if err != nil {
+		log.Printf("thumbnails for %s error: %v", f.Name, err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	duration := time.Duration(f.Duration * float64(time.Second))
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	fmt.Fprint(w, buildThumbnailVTT("/stage/"+f.ID+"/sprite.jpg", sheet, duration))
+}
+
+// handleStageDelete 放弃暂存文件
+func handleStageDelete(w http.ResponseWriter, r *http.Request) {
+	f, ok := getStagedOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	// 同时删除时只有一个请求能取出
+	if _, ok := staging.Take(f.ID); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f.removeSprite()
+	os.Remove(f.path)
+
+	w.WriteHeader(http.StatusNoContent)
+}
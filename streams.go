@@ -0,0 +1,257 @@
+//
+// FilePath    : video-trim\streams.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 流映射: 默认保留输入的全部流(多音轨、字幕、附件), 按容器支持情况转换或丢弃
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// 流类型, 与 ffprobe 输出的 codec_type 一致
+const (
+	streamVideo      = "video"
+	streamAudio      = "audio"
+	streamSubtitle   = "subtitle"
+	streamAttachment = "attachment"
+	streamData       = "data"
+)
+
+// allStreamTypes 全部流类型, 同时也是界面上显示的顺序
+var allStreamTypes = []string{streamVideo, streamAudio, streamSubtitle, streamAttachment, streamData}
+
+// textSubtitles 文本字幕编码, 可以在不同容器之间转换
+var textSubtitles = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"webvtt":   true,
+	"mov_text": true,
+	"text":     true,
+}
+
+// streamInfo ffprobe 输出的单个流信息
+type streamInfo struct {
+	Index     int    `json:"index"`      // 流序号
+	CodecType string `json:"codec_type"` // 流类型
+	CodecName string `json:"codec_name"` // 编码名称
+
+	Disposition struct {
+		AttachedPic int `json:"attached_pic"` // 是否为封面图片
+	} `json:"disposition"`
+
+	Tags struct {
+		Filename string `json:"filename"` // 附件文件名
+	} `json:"tags"`
+}
+
+// parseStreamTypes 解析流类型列表(逗号分隔, 可多个字段), 按固定顺序去重返回
+func parseStreamTypes(values []string) ([]string, error) {
+	selected := map[string]bool{}
+
+	for _, v := range values {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "" {
+				continue
+			}
+
+			if !isStreamType(t) {
+				return nil, fmt.Errorf("unknown stream type %q", t)
+			}
+
+			selected[t] = true
+		}
+	}
+
+	types := []string{}
+
+	for _, t := range allStreamTypes {
+		if selected[t] {
+			types = append(types, t)
+		}
+	}
+
+	if len(types) == 0 {
+		return nil, fmt.Errorf("at least one stream type must be kept")
+	}
+
+	return types, nil
+}
+
+// isStreamType 判断是否为支持的流类型
+func isStreamType(t string) bool {
+	for _, s := range allStreamTypes {
+		if s == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// probeStreams 使用 ffprobe 列出输入文件的所有流
+func probeStreams(ctx context.Context, absInput string) ([]streamInfo, error) {
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	cmd := newCommand(ctx, ffprobePath,
+		"-v", "error",
+		"-show_entries", "stream=index,codec_type,codec_name:stream_disposition=attached_pic:stream_tags=filename",
+		"-of", "json",
+		absInput,
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("probe streams: %w", err)
+	}
+
+	var res struct {
+		Streams []streamInfo `json:"streams"`
+	}
+
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("parse streams: %w", err)
+	}
+
+	return res.Streams, nil
+}
+
+// planOutputStreams 探测输入的流并生成映射参数, 无法探测时退回 ffmpeg 默认的流选择
+func planOutputStreams(ctx context.Context, absInput string, keep []string, ext string) ([]string, []string, error) {
+	streams, err := probeStreams(ctx, absInput)
+	if err != nil || len(streams) == 0 {
+		log.Printf("stream mapping skipped for %s: %v", absInput, err)
+		return nil, nil, nil
+	}
+
+	return planStreams(streams, keep, ext)
+}
+
+// containerSupport 判断容器(以扩展名表示)能否直接保存该流, 不能时返回可转换成的字幕编码(为空表示只能丢弃)
+func containerSupport(ext string, s streamInfo) (bool, string) {
+	text := textSubtitles[s.CodecName]
+
+	switch strings.ToLower(ext) {
+	case ".mp4", ".m4v", ".mov":
+		switch s.CodecType {
+		case streamSubtitle:
+			if s.CodecName == "mov_text" {
+				return true, ""
+			}
+
+			if text {
+				return false, "mov_text"
+			}
+
+			return false, ""
+		case streamAttachment:
+			return false, ""
+		case streamData:
+			// QuickTime 可以保存时间码等数据流, MP4 通常无法写入
+			return strings.ToLower(ext) == ".mov", ""
+		}
+	case ".mkv":
+		switch s.CodecType {
+		case streamSubtitle:
+			if s.CodecName == "mov_text" {
+				return false, "srt"
+			}
+
+			return true, ""
+		case streamData:
+			return false, ""
+		}
+	case ".webm":
+		switch s.CodecType {
+		case streamSubtitle:
+			if s.CodecName == "webvtt" {
+				return true, ""
+			}
+
+			if text {
+				return false, "webvtt"
+			}
+
+			return false, ""
+		case streamAttachment, streamData:
+			return false, ""
+		}
+	case ".ts", ".m2ts", ".mts":
+		switch s.CodecType {
+		case streamSubtitle:
+			return s.CodecName == "dvb_subtitle" || s.CodecName == "dvb_teletext", ""
+		case streamAttachment, streamData:
+			return false, ""
+		}
+	default:
+		// 其他容器(avi、flv 等)只保留音视频
+		switch s.CodecType {
+		case streamSubtitle, streamAttachment, streamData:
+			return false, ""
+		}
+	}
+
+	return isStreamType(s.CodecType), ""
+}
+
+// planStreams 生成流映射参数: 映射 keep 中类型的全部流, 容器无法保存的字幕转换为兼容编码, 其余丢弃并给出警告
+// 参数需放在 -c copy 之后, 以覆盖转换字幕的编码
+func planStreams(streams []streamInfo, keep []string, ext string) ([]string, []string, error) {
+	kept := map[string]bool{}
+	for _, t := range keep {
+		kept[t] = true
+	}
+
+	var (
+		args      []string
+		warnings  []string
+		mapped    int // 已映射的流数量
+		subtitles int // 已映射的字幕流数量, 用于 -c:s:N 指定输出字幕流
+	)
+
+	for _, s := range streams {
+		if !kept[s.CodecType] {
+			continue
+		}
+
+		ok, convert := containerSupport(ext, s)
+
+		switch {
+		case ok:
+		case convert != "":
+			args = append(args, "-c:s:"+strconv.Itoa(subtitles), convert)
+			warnings = append(warnings, fmt.Sprintf("%s stream #%d (%s) converted to %s for %s", s.CodecType, s.Index, s.CodecName, convert, ext))
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s stream #%d (%s) dropped: not supported in %s", s.CodecType, s.Index, s.CodecName, ext))
+			continue
+		}
+
+		if s.CodecType == streamSubtitle {
+			subtitles++
+		}
+
+		args = append(args, "-map", "0:"+strconv.Itoa(s.Index))
+		mapped++
+	}
+
+	if mapped == 0 {
+		return nil, warnings, fmt.Errorf("no streams left to keep for %s", ext)
+	}
+
+	return args, warnings, nil
+}
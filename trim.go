@@ -0,0 +1,228 @@
+//
+// FilePath    : video-trim\trim.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 裁剪参数与时间码解析
+//
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trimOptions 单个任务的裁剪参数
+type trimOptions struct {
+	Head    time.Duration // 掐头时长
+	Tail    time.Duration // 去尾时长
+	Cuts    []timeRange   // 区间列表, 含义由 CutMode 决定
+	CutMode string        // cutModeRemove 删除区间, cutModeKeep 只保留区间
+
+	TrimMode string // 掐头方式: trimModeFixed 固定时长, trimModeIntro 自动检测共同片头, trimModeReference 匹配参考片段
+	Snap     string // 片段起点对齐关键帧的方式: snapBack、snapForward、snapNearest 或 snapPrecise
+
+	Streams []string // 保留的流类型, 见 allStreamTypes
+
+	Cover   string        // 封面帧: coverNone 不设置, coverAuto 自动选择, coverAt 使用 CoverAt 位置的帧
+	CoverAt time.Duration // 封面帧在输出视频中的时间
+
+	Scrub bool // 去掉位置、设备、编码器和用户备注等元数据
+
+	ModTime time.Time // 单个文件在浏览器中的修改时间, 原视频没有拍摄时间时使用
+}
+
+// needsProcessing 判断参数是否会改变视频: 裁剪、区间列表、自动检测片头、设置封面、隐私清理或丢弃部分流
+func (o trimOptions) needsProcessing() bool {
+	return o.Head > 0 || o.Tail > 0 || len(o.Cuts) > 0 || o.TrimMode != trimModeFixed ||
+		o.Cover != coverNone || o.Scrub || len(o.Streams) < len(allStreamTypes)
+}
+
+// MarshalJSON 以秒为单位输出时长, 便于前端和脚本使用
+func (o trimOptions) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Head    float64     `json:"head"`
+		Tail    float64     `json:"tail"`
+		Cuts    []timeRange `json:"cuts,omitempty"`
+		CutMode string      `json:"cut_mode,omitempty"`
+		Mode    string      `json:"trim_mode"`
+		Snap    string      `json:"snap"`
+		Streams []string    `json:"streams"`
+		Cover   string      `json:"cover,omitempty"`
+		Scrub   bool        `json:"scrub,omitempty"`
+	}{
+		Head:    o.Head.Seconds(),
+		Tail:    o.Tail.Seconds(),
+		Cuts:    o.Cuts,
+		CutMode: o.CutMode,
+		Mode:    o.TrimMode,
+		Snap:    o.Snap,
+		Streams: o.Streams,
+		Cover:   o.coverValue(),
+		Scrub:   o.Scrub,
+	})
+}
+
+// coverValue 返回封面参数的表单形式: auto 或秒数
+func (o trimOptions) coverValue() string {
+	if o.Cover == coverAt {
+		return formatSeconds(o.CoverAt)
+	}
+
+	return o.Cover
+}
+
+// trimValueError 表单中的时间值无法解析
+type trimValueError struct {
+	Field string // 表单字段名
+	Value string // 原始输入
+}
+
+// Error 实现 error 接口
+func (e *trimValueError) Error() string {
+	return fmt.Sprintf("invalid %s value %q", e.Field, e.Value)
+}
+
+// parseTrimOptions 从请求中解析裁剪参数, 字段为空时使用配置的默认值
+func parseTrimOptions(r *http.Request) (trimOptions, error) {
+	opts := trimOptions{Head: headTrim, Tail: tailTrim, CutMode: cutModeRemove, TrimMode: trimModeFixed, Snap: keyframeSnap, Streams: streamTypes, Scrub: privacyScrub}
+
+	if s := strings.TrimSpace(r.FormValue("head")); s != "" {
+		d, err := parseTimecode(s)
+		if err != nil {
+			return opts, &trimValueError{Field: "head", Value: s}
+		}
+
+		opts.Head = d
+	}
+
+	if s := strings.TrimSpace(r.FormValue("tail")); s != "" {
+		d, err := parseTimecode(s)
+		if err != nil {
+			return opts, &trimValueError{Field: "tail", Value: s}
+		}
+
+		opts.Tail = d
+	}
+
+	// 区间列表可以是一个以逗号分隔的字段, 也可以是多个同名字段
+	for _, s := range r.Form["cuts"] {
+		ranges, err := parseTimeRanges(s)
+		if err != nil {
+			return opts, err
+		}
+
+		opts.Cuts = append(opts.Cuts, ranges...)
+	}
+
+	switch mode := r.FormValue("cut_mode"); mode {
+	case "", cutModeRemove:
+	case cutModeKeep:
+		opts.CutMode = cutModeKeep
+	default:
+		return opts, &trimValueError{Field: "cut_mode", Value: mode}
+	}
+
+	switch mode := r.FormValue("trim_mode"); mode {
+	case "", trimModeFixed:
+	case trimModeIntro, trimModeReference:
+		opts.TrimMode = mode
+	default:
+		return opts, &trimValueError{Field: "trim_mode", Value: mode}
+	}
+
+	if mode := r.FormValue("snap"); mode != "" {
+		if !validSnapMode(mode) {
+			return opts, &trimValueError{Field: "snap", Value: mode}
+		}
+
+		opts.Snap = mode
+	}
+
+	// 保留的流类型, 字段存在但为空表示一个都不保留, 视为无效
+	if values, ok := r.Form["streams"]; ok {
+		types, err := parseStreamTypes(values)
+		if err != nil {
+			return opts, &trimValueError{Field: "streams", Value: strings.Join(values, ",")}
+		}
+
+		opts.Streams = types
+	}
+
+	cover, at, err := parseCover(r.FormValue("cover"))
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Cover, opts.CoverAt = cover, at
+
+	if s := strings.TrimSpace(r.FormValue("scrub")); s != "" {
+		scrub, err := parseScrub(s)
+		if err != nil {
+			return opts, &trimValueError{Field: "scrub", Value: s}
+		}
+
+		opts.Scrub = scrub
+	}
+
+	return opts, nil
+}
+
+// parseTimecode 解析时间值, 支持小数秒(6.5)和 [HH:]MM:SS[.mmm] 格式, 不允许负数
+func parseTimecode(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty time value")
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time value %q", s)
+	}
+
+	var total float64
+
+	for i, p := range parts {
+		// 只有最后一段(秒)允许小数, 时和分必须为整数
+		last := i == len(parts)-1
+		if p == "" || strings.Trim(p, "0123456789.") != "" || (!last && strings.Contains(p, ".")) {
+			return 0, fmt.Errorf("invalid time value %q", s)
+		}
+
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time value %q", s)
+		}
+
+		// 时间码中的分和秒不能超过 59
+		if len(parts) > 1 && i > 0 && v >= 60 {
+			return 0, fmt.Errorf("invalid time value %q", s)
+		}
+
+		total = total*60 + v
+	}
+
+	// 超出 time.Duration 可表示范围的值视为无效
+	if total*float64(time.Second) >= math.MaxInt64 {
+		return 0, fmt.Errorf("time value %q out of range", s)
+	}
+
+	return time.Duration(math.Round(total * float64(time.Second))), nil
+}
+
+// formatSeconds 将时长格式化为最简的秒数字符串, 例如 6.5
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// formatFFmpegTime 将时长格式化为 ffmpeg 参数使用的秒数
+// 精确到微秒, 与 ffprobe 输出的时间戳精度一致, 保证对齐后的起点恰好落在关键帧上
+func formatFFmpegTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 6, 64)
+}
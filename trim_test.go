@@ -0,0 +1,91 @@
+//
+// FilePath    : video-trim\trim_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 时间码解析和裁剪参数的测试
+//
+
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// secs 将秒数转换为时长, 用于编写测试数据
+func secs(v float64) time.Duration {
+	return time.Duration(math.Round(v * float64(time.Second)))
+}
+
+func TestParseTimecode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "6", want: secs(6)},
+		{in: "6.5", want: secs(6.5)},
+		{in: " 5 ", want: secs(5)},
+		{in: "0", want: 0},
+		{in: "01:30", want: secs(90)},
+		{in: "00:00:06.500", want: secs(6.5)},
+		{in: "1:02:03.250", want: secs(3723.25)},
+		{in: "100:00", want: secs(6000)},
+		{in: "", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "1:60", wantErr: true},
+		{in: "1:00:60", wantErr: true},
+		{in: "1::2", wantErr: true},
+		{in: "1:2:3:4", wantErr: true},
+		{in: "1.5:00", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "99999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimecode(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTimecode(%q) = %v, want error", tt.in, got)
+			}
+
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("parseTimecode(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestTrimOptionsNeedsProcessing(t *testing.T) {
+	base := trimOptions{CutMode: cutModeRemove, TrimMode: trimModeFixed, Streams: allStreamTypes}
+
+	tests := []struct {
+		name string
+		edit func(o *trimOptions)
+		want bool
+	}{
+		{name: "nothing", edit: func(*trimOptions) {}, want: false},
+		{name: "head", edit: func(o *trimOptions) { o.Head = secs(1) }, want: true},
+		{name: "tail", edit: func(o *trimOptions) { o.Tail = secs(1) }, want: true},
+		{name: "cuts", edit: func(o *trimOptions) { o.Cuts = []timeRange{{Start: secs(1), End: secs(2)}} }, want: true},
+		{name: "intro detection", edit: func(o *trimOptions) { o.TrimMode = trimModeIntro }, want: true},
+		{name: "cover", edit: func(o *trimOptions) { o.Cover = coverAuto }, want: true},
+		{name: "scrub", edit: func(o *trimOptions) { o.Scrub = true }, want: true},
+		{name: "streams", edit: func(o *trimOptions) { o.Streams = []string{streamVideo} }, want: true},
+	}
+
+	for _, tt := range tests {
+		o := base
+		tt.edit(&o)
+
+		if got := o.needsProcessing(); got != tt.want {
+			t.Errorf("%s: needsProcessing() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+//
+// FilePath    : video-trim\tus.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 断点续传: 实现 tus 1.0.0 协议(creation、termination), 上传完成后可提交裁剪
+//
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tusVersion 支持的 tus 协议版本
+const tusVersion = "1.0.0"
+
+// tusIDPattern 上传 ID 格式(与 newJobID 一致), 防止路径穿越
+var tusIDPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// tusUpload 一个 tus 上传, 信息保存在 uploadDir 下的 JSON 文件中, 服务重启后仍可继续上传
+// 已上传的字节数即数据文件的大小
+type tusUpload struct {
+	ID        string            `json:"id"`                 // 上传 ID
+	Length    int64             `json:"length"`             // 文件总大小(字节)
+	Metadata  map[string]string `json:"metadata,omitempty"` // 客户端提供的元数据(filename、lastModified 等)
+	CreatedAt time.Time         `json:"created_at"`         // 创建时间
+	Owner     string            `json:"owner,omitempty"`    // 创建上传的会话 ID
+}
+
+// tusLock 一个上传的写入锁, refs 为持有和等待该锁的数量
+type tusLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// tusLocks 每个上传的写入锁, 同一上传的 PATCH、删除和提交不能同时进行
+// 没有人持有或等待时删除, 避免已结束的上传一直占用内存
+var tusLocks = struct {
+	mu    sync.Mutex
+	locks map[string]*tusLock
+}{locks: map[string]*tusLock{}}
+
+// lockTus 锁定指定上传, 返回解锁函数
+func lockTus(id string) func() {
+	tusLocks.mu.Lock()
+
+	l, ok := tusLocks.locks[id]
+	if !ok {
+		l = &tusLock{}
+		tusLocks.locks[id] = l
+	}
+
+	l.refs++
+	tusLocks.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		tusLocks.mu.Lock()
+		defer tusLocks.mu.Unlock()
+
+		if l.refs--; l.refs == 0 {
+			delete(tusLocks.locks, id)
+		}
+	}
+}
+
+// tusDataPath 上传数据文件路径
+func tusDataPath(id string) string {
+	return filepath.Join(uploadDir, "tus_"+id+".part")
+}
+
+// tusInfoPath 上传信息文件路径
+func tusInfoPath(id string) string {
+	return filepath.Join(uploadDir, "tus_"+id+".json")
+}
+
+// Filename 返回客户端提供的文件名, 未提供时使用 ID
+func (u *tusUpload) Filename() string {
+	if name := filepath.Base(u.Metadata["filename"]); name != "" && name != "." && name != string(filepath.Separator) {
+		return name
+	}
+
+	return u.ID + ".mp4"
+}
+
+// Offset 返回已上传的字节数
+func (u *tusUpload) Offset() (int64, error) {
+	fi, err := os.Stat(tusDataPath(u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	return fi.Size(), nil
+}
+
+// loadTusUpload 读取上传信息, 不存在时返回 os.ErrNotExist
+func loadTusUpload(id string) (*tusUpload, error) {
+	if !tusIDPattern.MatchString(id) {
+		return nil, os.ErrNotExist
+	}
+
+	b, err := os.ReadFile(tusInfoPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var u tusUpload
+	if err := json.Unmarshal(b, &u); err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// removeTusUpload 删除上传的数据和信息文件
+func removeTusUpload(id string) {
+	os.Remove(tusDataPath(id))
+	os.Remove(tusInfoPath(id))
+}
+
+// removeTusUploads 删除 match 返回 true 的全部上传, 删除时持有上传的写入锁, 不会与正在进行的 PATCH 冲突
+func removeTusUploads(match func(u *tusUpload) bool) {
+	entries, err := os.ReadDir(uploadDir)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		id, ok := tusFileID(e.Name())
+		if !ok || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+
+		if u, err := loadTusUpload(id); err == nil && match(u) {
+			unlock := lockTus(id)
+			removeTusUpload(id)
+			unlock()
+		}
+	}
+}
+
+// parseTusMetadata 解析 Upload-Metadata 头: 逗号分隔的 "key base64(value)" 列表, 值可以省略
+func parseTusMetadata(s string) (map[string]string, error) {
+	md := map[string]string{}
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, enc, _ := strings.Cut(pair, " ")
+
+		v, err := base64.StdEncoding.DecodeString(strings.TrimSpace(enc))
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata value for %q: %w", key, err)
+		}
+
+		md[key] = string(v)
+	}
+
+	return md, nil
+}
+
+// tusHeaders 设置每个 tus 响应都需要的头
+func tusHeaders(w http.ResponseWriter) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// checkTusVersionOrRespond 检查客户端的协议版本, 不支持时响应 412
+func checkTusVersionOrRespond(w http.ResponseWriter, r *http.Request) bool {
+	tusHeaders(w)
+
+	if v := r.Header.Get("Tus-Resumable"); v != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		http.Error(w, "unsupported tus version "+strconv.Quote(v), http.StatusPreconditionFailed)
+
+		return false
+	}
+
+	return true
+}
+
+// loadTusUploadOrRespond 根据路径中的 ID 读取上传信息, 不存在或不属于本会话时响应 404
+func loadTusUploadOrRespond(w http.ResponseWriter, r *http.Request) (*tusUpload, bool) {
+	u, err := loadTusUpload(r.PathValue("id"))
+	if err == nil && !canAccess(r, u.Owner) {
+		err = os.ErrNotExist
+	}
+
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("load tus upload %s error: %v", r.PathValue("id"), err)
+		}
+
+		http.NotFound(w, r)
+
+		return nil, false
+	}
+
+	return u, true
+}
+
+// handleTusOptions 返回服务器支持的协议版本和扩展
+func handleTusOptions(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", "creation,termination")
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxUploadSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTusCreate 创建上传(creation 扩展), 需要 Upload-Length, 不支持延迟指定长度
+func handleTusCreate(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersionOrRespond(w, r) {
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+
+	if length > maxUploadSize {
+		http.Error(w, "upload exceeds "+humanReadableBytes(maxUploadSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !checkDiskSpaceOrRespond(w, r, uploadSpaceFactor*length) {
+		return
+	}
+
+	md, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u := &tusUpload{ID: newJobID(), Length: length, Metadata: md, CreatedAt: time.Now(), Owner: requestSession(r)}
+
+	b, err := json.Marshal(u)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := os.WriteFile(tusDataPath(u.ID), nil, 0600); err != nil {
+		log.Printf("create tus upload error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if err := os.WriteFile(tusInfoPath(u.ID), b, 0600); err != nil {
+		removeTusUpload(u.ID)
+		log.Printf("create tus upload error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Location", "/tus/"+u.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleTusHead 返回上传进度
+func handleTusHead(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersionOrRespond(w, r) {
+		return
+	}
+
+	u, ok := loadTusUploadOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	offset, err := u.Offset()
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleTusPatch 从 Upload-Offset 处追加数据
+// 连接中断时已收到的数据会保留, 客户端通过 HEAD 获取进度后继续上传
+func handleTusPatch(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersionOrRespond(w, r) {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	u, ok := loadTusUploadOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	unlock := lockTus(u.ID)
+	defer unlock()
+
+	offset, err := u.Offset()
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if v, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64); err != nil || v != offset {
+		http.Error(w, "Upload-Offset does not match current offset "+strconv.FormatInt(offset, 10), http.StatusConflict)
+		return
+	}
+
+	f, err := os.OpenFile(tusDataPath(u.ID), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body := &deadlineReader{r: r.Body, rc: http.NewResponseController(w)}
+
+	n, copyErr := io.Copy(f, io.LimitReader(body, u.Length-offset))
+	closeErr := f.Close()
+	end := offset + n
+
+	if copyErr != nil || closeErr != nil {
+		log.Printf("tus upload %s interrupted at %d/%d: %v %v", u.ID, end, u.Length, copyErr, closeErr)
+	}
+
+	// 开头的数据到齐后校验魔法数字, 不是视频时删除整个上传
+	if head := min(int64(sniffLen), u.Length); offset < head && end >= head && !tusLooksLikeVideo(u.ID, head) {
+		removeTusUpload(u.ID)
+		http.Error(w, getLocale(detectLangFromRequest(r))[KeyNotSupportedVideo], http.StatusUnsupportedMediaType)
+
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(end, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deadlineReader 每次读取时顺延连接的读写超时
+// 服务器的 read_timeout_seconds 限制整个请求, 大文件在慢速网络上会超时; 顺延后只在连接停滞时超时(tus 和表单上传共用)
+type deadlineReader struct {
+	r  io.Reader
+	rc *http.ResponseController
+}
+
+// Read 实现 io.Reader
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	d.rc.SetReadDeadline(deadlineAfter(readTimeoutSeconds))
+	d.rc.SetWriteDeadline(deadlineAfter(writeTimeoutSeconds))
+
+	return d.r.Read(p)
+}
+
+// tusLooksLikeVideo 读取数据文件开头 n 个字节校验魔法数字
+func tusLooksLikeVideo(id string, n int64) bool {
+	f, err := os.Open(tusDataPath(id))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return false
+	}
+
+	return isVideoMagic(buf)
+}
+
+// handleTusDelete 放弃上传(termination 扩展)
+func handleTusDelete(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersionOrRespond(w, r) {
+		return
+	}
+
+	u, ok := loadTusUploadOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	unlock := lockTus(u.ID)
+	defer unlock()
+
+	removeTusUpload(u.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getTusUploadsOrRespond 根据表单中的 tus 字段查找已完成的上传, 不存在或未完成时直接响应
+func getTusUploadsOrRespond(w http.ResponseWriter, r *http.Request, lang string) ([]*tusUpload, bool) {
+	uploads := []*tusUpload{}
+
+	for _, id := range r.PostForm["tus"] {
+		u, err := loadTusUpload(id)
+		if err == nil && !canAccess(r, u.Owner) {
+			err = os.ErrNotExist
+		}
+
+		if err == nil {
+			if offset, oerr := u.Offset(); oerr != nil || offset != u.Length {
+				err = fmt.Errorf("upload incomplete")
+			}
+		}
+
+		if err != nil {
+			http.Error(w, fmt.Sprintf(getLocale(lang)[KeyTusNotFound], id), http.StatusBadRequest)
+			return nil, false
+		}
+
+		uploads = append(uploads, u)
+	}
+
+	return uploads, true
+}
+
+// takeTusUploads 将已完成的上传移动到任务的工作目录作为输入文件, 并删除上传信息
+// 任一文件移动失败时将已移动的文件移回, 上传仍可再次提交
+func takeTusUploads(jobID string, uploads []*tusUpload) ([]*uploadedFile, error) {
+	files := make([]*uploadedFile, 0, len(uploads))
+
+	for idx, u := range uploads {
+		path := filepath.Join(jobUploadDir(jobID), fmt.Sprintf("input_t%d%s", idx, uploadExt(u.Filename())))
+
+		unlock := lockTus(u.ID)
+		err := os.Rename(tusDataPath(u.ID), path)
+		unlock()
+
+		if err != nil {
+			for i, f := range files {
+				os.Rename(f.Path, tusDataPath(uploads[i].ID))
+			}
+
+			return nil, err
+		}
+
+		files = append(files, &uploadedFile{Filename: u.Filename(), Size: u.Length, Path: path})
+	}
+
+	for _, u := range uploads {
+		os.Remove(tusInfoPath(u.ID))
+	}
+
+	return files, nil
+}
@@ -0,0 +1,73 @@
+//
+// FilePath    : video-trim\tus_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 断点续传元数据解析的测试
+//
+
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestParseTusMetadata(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{in: "", want: map[string]string{}},
+		{in: "filename d29ybGQ=", want: map[string]string{"filename": "world"}},
+		{in: "filename YS5tcDQ=,is_confidential", want: map[string]string{"filename": "a.mp4", "is_confidential": ""}},
+		{in: " filename  YS5tcDQ= , filetype dmlkZW8vbXA0 ,", want: map[string]string{"filename": "a.mp4", "filetype": "video/mp4"}},
+		{in: "filename 6KeG6aKRLm1wNA==", want: map[string]string{"filename": "视频.mp4"}},
+		{in: "filename !!!", wantErr: true},
+		{in: "filename YS5tcDQ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTusMetadata(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTusMetadata(%q) = %v, want error", tt.in, got)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTusMetadata(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestLockTusReleasesEntries(t *testing.T) {
+	var wg sync.WaitGroup
+
+	counter := 0
+
+	for range 50 {
+		wg.Go(func() {
+			unlock := lockTus("0123456789abcdef")
+			counter++
+			unlock()
+		})
+	}
+
+	wg.Wait()
+
+	if counter != 50 {
+		t.Errorf("counter = %d, want 50", counter)
+	}
+
+	tusLocks.mu.Lock()
+	defer tusLocks.mu.Unlock()
+
+	if n := len(tusLocks.locks); n != 0 {
+		t.Errorf("%d tus locks left after all unlocked, want 0", n)
+	}
+}
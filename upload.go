@@ -0,0 +1,302 @@
+//
+// FilePath    : video-trim\upload.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 流式上传: 逐个读取 multipart 分段, 文件直接写入目标路径, 边写边限制大小并校验魔法数字
+//
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// 表单普通字段的总大小限制(字节), 与 net/http 解析普通表单的默认限制一致
+const maxFormValueBytes = 10 << 20
+
+// sniffLen 魔法数字校验读取的开头字节数
+const sniffLen = 64
+
+// 上传文件不符合要求的原因
+const (
+	uploadTooLarge = "too_large" // 超过 maxUploadSize
+	uploadEmpty    = "empty"     // 空文件
+	uploadNotVideo = "not_video" // 魔法数字校验失败
+	uploadReadFail = "read"      // 读取请求失败(如客户端中断)
+	uploadSaveFail = "save"      // 写入磁盘失败
+)
+
+// uploadedFile 已写入磁盘的上传文件
+type uploadedFile struct {
+	Filename string // 上传时的原始文件名
+	Size     int64  // 文件大小(字节)
+	Path     string // 保存路径
+}
+
+// uploadForm 流式解析的 multipart 表单: 普通字段保存在内存中, 文件直接写入磁盘
+type uploadForm struct {
+	Value url.Values      // 普通字段
+	Files []*uploadedFile // 按上传顺序排列的文件
+}
+
+// RemoveFiles 删除已保存的上传文件, 用于请求被拒绝时清理
+func (f *uploadForm) RemoveFiles() {
+	for _, u := range f.Files {
+		os.Remove(u.Path)
+	}
+}
+
+// uploadError 上传文件不符合要求或无法保存
+type uploadError struct {
+	Kind     string // 原因, 见 uploadTooLarge 等
+	Filename string // 出错的文件名
+	Err      error  // 底层错误
+}
+
+// Error 实现 error 接口
+func (e *uploadError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("upload %s: %s: %v", e.Filename, e.Kind, e.Err)
+	}
+
+	return fmt.Sprintf("upload %s: %s", e.Filename, e.Kind)
+}
+
+// Unwrap 返回底层错误
+func (e *uploadError) Unwrap() error {
+	return e.Err
+}
+
+// pathFunc 返回第 idx 个上传文件的保存路径
+type pathFunc func(idx int, filename string) string
+
+// readUploadForm 逐个读取 multipart 分段: fileField 字段的文件直接写入 pathFor 返回的路径, 其他字段保存在内存中
+// 出错时删除已保存的全部文件; 成功后表单字段同时写入 r.Form/r.PostForm, 可继续使用 r.FormValue
+func readUploadForm(r *http.Request, fileField string, pathFor pathFunc) (*uploadForm, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	form := &uploadForm{Value: url.Values{}}
+	remaining := int64(maxFormValueBytes)
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			form.RemoveFiles()
+			return nil, err
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+
+		// 未选择文件时浏览器也会提交一个文件名为空的分段
+		if part.FileName() == "" {
+			if _, isFile := part.Header["Content-Type"]; isFile && name == fileField {
+				part.Close()
+				continue
+			}
+
+			b, err := io.ReadAll(io.LimitReader(part, remaining+1))
+			part.Close()
+
+			if err != nil {
+				form.RemoveFiles()
+				return nil, err
+			}
+
+			remaining -= int64(len(b))
+			if remaining < 0 {
+				form.RemoveFiles()
+				return nil, multipart.ErrMessageTooLarge
+			}
+
+			form.Value.Add(name, string(b))
+
+			continue
+		}
+
+		// 其他字段的文件不接收
+		if name != fileField {
+			part.Close()
+			continue
+		}
+
+		u, err := saveUploadPart(part, pathFor(len(form.Files), part.FileName()))
+		part.Close()
+
+		if err != nil {
+			form.RemoveFiles()
+			return nil, err
+		}
+
+		form.Files = append(form.Files, u)
+	}
+
+	// 合并查询参数, 与 ParseMultipartForm 的行为一致
+	r.PostForm = form.Value
+	r.Form = url.Values{}
+
+	for k, v := range r.URL.Query() {
+		r.Form[k] = append(r.Form[k], v...)
+	}
+
+	for k, v := range form.Value {
+		r.Form[k] = append(r.Form[k], v...)
+	}
+
+	return form, nil
+}
+
+// saveUploadPart 将一个文件分段写入 path: 先读取开头字节校验魔法数字, 再边写边检查大小, 不会重新打开文件
+func saveUploadPart(part *multipart.Part, path string) (*uploadedFile, error) {
+	filename := part.FileName()
+
+	buf := make([]byte, sniffLen)
+
+	n, err := io.ReadFull(part, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, &uploadError{Kind: uploadReadFail, Filename: filename, Err: err}
+	}
+
+	if n == 0 {
+		return nil, &uploadError{Kind: uploadEmpty, Filename: filename}
+	}
+
+	if !isVideoMagic(buf[:n]) {
+		return nil, &uploadError{Kind: uploadNotVideo, Filename: filename}
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, &uploadError{Kind: uploadSaveFail, Filename: filename, Err: err}
+	}
+
+	// 写入失败时删除不完整的文件
+	fail := func(kind string, err error) (*uploadedFile, error) {
+		out.Close()
+		os.Remove(path)
+
+		return nil, &uploadError{Kind: kind, Filename: filename, Err: err}
+	}
+
+	if _, err := out.Write(buf[:n]); err != nil {
+		return fail(uploadSaveFail, err)
+	}
+
+	// 多读一个字节用于判断是否超出限制
+	size := int64(n)
+	limit := maxUploadSize - size + 1
+
+	copied, err := io.Copy(out, io.LimitReader(part, max(limit, 0)))
+	size += copied
+
+	switch {
+	case err != nil && isWriteError(err):
+		return fail(uploadSaveFail, err)
+	case err != nil:
+		return fail(uploadReadFail, err)
+	case size > maxUploadSize:
+		return fail(uploadTooLarge, nil)
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(path)
+		return nil, &uploadError{Kind: uploadSaveFail, Filename: filename, Err: err}
+	}
+
+	return &uploadedFile{Filename: filename, Size: size, Path: path}, nil
+}
+
+// isWriteError 判断 io.Copy 的错误是否来自写入磁盘(而不是读取请求)
+func isWriteError(err error) bool {
+	var pathErr *os.PathError
+
+	return errors.As(err, &pathErr)
+}
+
+// readUploadFormOrRespond 流式读取上传表单, 出错时按原因直接响应
+func readUploadFormOrRespond(w http.ResponseWriter, r *http.Request, fileField string, pathFor pathFunc) (*uploadForm, bool) {
+	// 与 tus 上传相同, 读取时顺延连接超时, 大文件上传只在连接停滞时才会超时
+	r.Body = io.NopCloser(&deadlineReader{r: r.Body, rc: http.NewResponseController(w)})
+
+	form, err := readUploadForm(r, fileField, pathFor)
+	if err == nil {
+		return form, true
+	}
+
+	lang := detectLangFromRequest(r)
+	i18n := getLocale(lang)
+
+	var ue *uploadError
+	if !errors.As(err, &ue) {
+		log.Printf("read upload form error: %v", err)
+
+		if errors.Is(err, multipart.ErrMessageTooLarge) {
+			msg := fmt.Sprintf(i18n[KeyRequestBodyTooLarge], humanReadableBytes(maxFormValueBytes))
+			http.Error(w, msg, http.StatusRequestEntityTooLarge)
+
+			return nil, false
+		}
+
+		http.Error(w, i18n[KeyRequestParseError], http.StatusBadRequest)
+
+		return nil, false
+	}
+
+	switch ue.Kind {
+	case uploadTooLarge:
+		msg := i18n[KeyFileTooLargePrefix] + ue.Filename + i18n[KeyFileTooLargeSuffix] + humanReadableBytes(maxUploadSize) + i18n[KeyFileTooLargeEnd]
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintln(w, msg)
+	case uploadEmpty:
+		respondAlertAndRedirect(w, fmt.Sprintf(i18n[KeyFileEmptyOrUnreadable], ue.Filename))
+	case uploadNotVideo:
+		respondAlertAndRedirect(w, fmt.Sprintf(i18n[KeyNotSupportedVideo], ue.Filename))
+	case uploadReadFail:
+		log.Printf("read uploaded file error: %v", err)
+		respondAlertAndRedirect(w, fmt.Sprintf(i18n[KeyCannotReadFile], ue.Filename))
+	default:
+		log.Printf("save uploaded file error: %v", err)
+		http.Error(w, i18n[KeyUploadFailed]+ue.Err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil, false
+}
+
+// respondSelectAtLeastOne 提示至少选择一个文件
+func respondSelectAtLeastOne(w http.ResponseWriter, r *http.Request) {
+	msg := getLocale(detectLangFromRequest(r))[KeySelectAtLeastOne]
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<script>alert(%s);location.href='/';</script>`, strconv.Quote(msg))
+}
+
+// uploadExt 推断上传文件的扩展名, 默认使用 .mp4
+func uploadExt(filename string) string {
+	if ext := filepath.Ext(filename); ext != "" {
+		return ext
+	}
+
+	return ".mp4"
+}
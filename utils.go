@@ -9,13 +9,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"io"
 	"log"
-	"mime/multipart"
-	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -35,7 +33,7 @@ var TemplateFuncMap = template.FuncMap{
 
 // 初始化目录
 func initDir() {
-	// 初始化, 确保上传和输出目录存在
+	// 初始化, 确保上传、输出、参考片段和状态目录存在
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
 		log.Fatalf("failed to create upload directory: %v", err)
 	}
@@ -43,52 +41,15 @@ func initDir() {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		log.Fatalf("failed to create output directory: %v", err)
 	}
-}
 
-// getLocalIP 获取本机局域网 IP, 用于提示用户
-func getLocalIP() string {
-	// 获取本机网络接口地址列表
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		return "127.0.0.1"
-	}
-	// 选择第一个非回环的 IPv4 地址作为局域网提示
-	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String()
-			}
-		}
-	}
-
-	return "127.0.0.1"
-}
-
-// parseHeadSec 从请求中解析裁剪开头秒数
-func parseHeadSec(r *http.Request) int {
-	// 从表单中解析裁剪开头秒数, 若无或无效则使用默认值
-	head := headTrimSeconds
-
-	if headStr := r.FormValue("head"); headStr != "" {
-		if v, err := strconv.Atoi(headStr); err == nil && v >= 0 {
-			head = v
-		}
+	if err := os.MkdirAll(referenceDir, 0755); err != nil {
+		log.Fatalf("failed to create reference directory: %v", err)
 	}
 
-	return head
-}
-
-// parseTailSec 从请求中解析去尾秒数
-func parseTailSec(r *http.Request) int {
-	tail := tailSeconds
-
-	if tailStr := r.FormValue("tail"); tailStr != "" {
-		if v, err := strconv.Atoi(tailStr); err == nil && v >= 0 {
-			tail = v
-		}
+	// 状态目录保存会话签名密钥, 只允许当前用户访问
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		log.Fatalf("failed to create state directory: %v", err)
 	}
-
-	return tail
 }
 
 // ensurePostMethod 确保请求方法为 POST, 否则直接响应错误
@@ -101,116 +62,6 @@ func ensurePostMethod(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
-// parseMultipartFormOrRespond 解析 multipart 表单并在出错时直接响应
-func parseMultipartFormOrRespond(w http.ResponseWriter, r *http.Request) bool {
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		// 选择语言并加载翻译
-		lang := detectLangFromRequest(r)
-		i18n := getLocale(lang)
-
-		if strings.Contains(err.Error(), "request body too large") || strings.Contains(err.Error(), "http: request body too large") {
-			msg := fmt.Sprintf(i18n[KeyRequestBodyTooLarge], humanReadableBytes(maxUploadSize))
-
-			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-			w.WriteHeader(http.StatusRequestEntityTooLarge)
-			fmt.Fprintln(w, msg)
-
-			return false
-		}
-
-		// 通用解析错误信息
-		http.Error(w, i18n[KeyRequestParseError], http.StatusBadRequest)
-
-		return false
-	}
-
-	return true
-}
-
-// getUploadedFilesOrRespond 获取上传的文件列表并在无文件时直接响应
-func getUploadedFilesOrRespond(w http.ResponseWriter, r *http.Request) ([]*multipart.FileHeader, bool) {
-	files := r.MultipartForm.File["videos"]
-	if len(files) == 0 {
-		// 选择语言并加载翻译
-		lang := detectLangFromRequest(r)
-		i18n := getLocale(lang)
-
-		msg := i18n[KeySelectAtLeastOne]
-
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, `<script>alert(%s);location.href='/';</script>`, strconv.Quote(msg))
-
-		return nil, false
-	}
-
-	return files, true
-}
-
-// checkFilesSizeOrRespond 检查上传文件大小并在超出限制时直接响应
-func checkFilesSizeOrRespond(w http.ResponseWriter, files []*multipart.FileHeader, lang string) bool {
-	for _, hdr := range files {
-		if hdr.Size > maxUploadSize {
-			i18n := getLocale(lang)
-
-			prefix := i18n[KeyFileTooLargePrefix]
-			suffix := i18n[KeyFileTooLargeSuffix]
-			end := i18n[KeyFileTooLargeEnd]
-
-			msg := prefix + hdr.Filename + suffix + humanReadableBytes(maxUploadSize) + end
-
-			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-			w.WriteHeader(http.StatusRequestEntityTooLarge)
-			fmt.Fprintln(w, msg)
-
-			return false
-		}
-	}
-
-	return true
-}
-
-// checkFilesMagicOrRespond 使用魔法数字(文件签名)校验上传文件是否为视频格式
-func checkFilesMagicOrRespond(w http.ResponseWriter, files []*multipart.FileHeader, lang string) bool {
-	const sniffLen = 64
-
-	i18n := getLocale(lang)
-
-	for _, hdr := range files {
-		f, err := hdr.Open()
-		if err != nil {
-			log.Printf("open uploaded file error: %v", err)
-			respondAlertAndRedirect(w, fmt.Sprintf(i18n[KeyCannotReadFile], hdr.Filename))
-
-			return false
-		}
-
-		buf := make([]byte, sniffLen)
-		n, err := io.ReadFull(f, buf)
-		f.Close()
-
-		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-			log.Printf("read uploaded file error: %v", err)
-			respondAlertAndRedirect(w, fmt.Sprintf(i18n[KeyCannotReadFile], hdr.Filename))
-
-			return false
-		}
-
-		if n == 0 {
-			respondAlertAndRedirect(w, fmt.Sprintf(i18n[KeyFileEmptyOrUnreadable], hdr.Filename))
-
-			return false
-		}
-
-		if !isVideoMagic(buf[:n]) {
-			respondAlertAndRedirect(w, fmt.Sprintf(i18n[KeyNotSupportedVideo], hdr.Filename))
-
-			return false
-		}
-	}
-
-	return true
-}
-
 // isVideoMagic 根据常见视频文件的魔法数字(文件签名)判断是否可能为视频
 func isVideoMagic(b []byte) bool {
 	// 检查 MP4 / MOV: 通常在偏移 4 处包含 "ftyp"
@@ -245,68 +96,39 @@ func isVideoMagic(b []byte) bool {
 	return false
 }
 
-// processSingleFile 保存上传文件、调用 ffmpeg, 并返回输出文件名
-func processSingleFile(hdr *multipart.FileHeader, idx int, headSec int, tailSec int) (string, error) {
-	// 打开上传的文件头, 获取读取流
-	f, err := hdr.Open()
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
+// processSingleFile 对已保存的输入文件调用 ffmpeg, 输出写入任务的工作目录 workDir, 返回输出文件路径
+// 调用方完成封面等后续处理后通过 publishOutput 移入输出目录
+func processSingleFile(ctx context.Context, inputPath, workDir, filename string, opts trimOptions, onProgress progressFunc, onPlan planFunc) (string, error) {
+	// 处理完成(无论成功与否)后删除临时输入文件
+	defer os.Remove(inputPath)
 
 	// 推断文件扩展名, 默认使用 .mp4
-	ext := filepath.Ext(hdr.Filename)
+	ext := filepath.Ext(filename)
 	if ext == "" {
 		ext = ".mp4"
 	}
 
-	base := filepath.Base(hdr.Filename)
+	base := filepath.Base(filename)
 	nameOnly := strings.TrimSuffix(base, ext)
 
-	// 在 uploads 目录生成临时输入文件路径, 防止文件名冲突
-	inputPath := filepath.Join(uploadDir, fmt.Sprintf("input_%d_%d%s", time.Now().Unix(), idx, ext))
-
-	// 将上传文件写入磁盘
-	outFile, err := os.Create(inputPath)
-	if err != nil {
-		return "", err
-	}
-
-	if _, err := io.Copy(outFile, f); err != nil {
-		outFile.Close()
-		os.Remove(inputPath)
-
-		return "", err
-	}
-
-	outFile.Close()
-
-	// 生成输出文件名并确保不会覆盖已有文件
-	outName := fmt.Sprintf("%s-cut%s", nameOnly, ext)
-	outputPath := filepath.Join(outputDir, outName)
-
-	if _, err := os.Stat(outputPath); err == nil {
-		outName = fmt.Sprintf("%s-cut-%d%s", nameOnly, time.Now().Unix(), ext)
-		outputPath = filepath.Join(outputDir, outName)
-	}
+	// 工作目录只属于当前任务, 且上一个文件已移出, 不会与其他文件冲突; 最终文件名在发布时确定
+	outputPath := filepath.Join(workDir, nameOnly+"-cut"+ext)
 
 	// 调用 ffmpeg 进行剪切处理
-	if err := runFFmpeg(inputPath, outputPath, headSec, tailSec); err != nil {
-		// 如果处理失败, 清理临时输入文件并返回错误
-		os.Remove(inputPath)
+	if err := runFFmpeg(ctx, inputPath, outputPath, opts, onProgress, onPlan); err != nil {
+		// 删除被中断或失败时残留的不完整输出
+		os.Remove(outputPath)
 		return "", err
 	}
 
-	// 处理完成后删除临时输入文件并返回输出文件名
-	os.Remove(inputPath)
-
-	return outName, nil
+	return outputPath, nil
 }
 
 // runFFmpeg 简单包装 ffmpeg 调用, 校验并规范化参数以避免可控的命令注入
-func runFFmpeg(inputPath, outputPath string, headSec int, tailSec int) error {
-	// 执行流程：校验参数 -> 解析并校验路径 -> 构建参数 -> 执行 ffmpeg
-	if err := validateHeadTail(&headSec, tailSec); err != nil {
+// onPlan 在开始导出前报告计划的片段、对齐关键帧后实际导出的片段以及流映射警告
+func runFFmpeg(ctx context.Context, inputPath, outputPath string, opts trimOptions, onProgress progressFunc, onPlan planFunc) error {
+	// 执行流程：校验参数 -> 解析并校验路径 -> 计算片段 -> 对齐关键帧 -> 构建参数 -> 执行 ffmpeg
+	if err := validateHeadTail(&opts.Head, opts.Tail); err != nil {
 		return err
 	}
 
@@ -322,126 +144,214 @@ func runFFmpeg(inputPath, outputPath string, headSec int, tailSec int) error {
 		return err
 	}
 
-	// 构建 ffmpeg 参数
-	args, err := buildFFmpegArgs(absInput, absOutput, headSec, tailSec)
+	// 获取媒体时长, 去尾或多段裁剪时必须获取, 否则仅用于计算进度
+	duration, err := probeDurationForTrim(ctx, absInput, opts.Tail > 0 || len(opts.Cuts) > 0)
 	if err != nil {
 		return err
 	}
 
-	// 执行 ffmpeg 命令
-	cmd := exec.Command(ffmpegPath, args...)
+	// 根据时长规范化需要保留的片段
+	segments, err := planSegments(opts, duration)
+	if err != nil {
+		return err
+	}
+
+	// 流复制只能从关键帧开始, 按对齐方式调整片段起点, 使实际剪切位置可预期
+	// 精确模式下片段不变, 剪切点到下一个关键帧之间重新编码
+	requested := segments
+	pieces := copyPieces(segments)
+
+	if needsKeyframes(segments) {
+		keyframes, err := probeKeyframes(ctx, absInput)
+		if err != nil || len(keyframes) == 0 {
+			log.Printf("keyframe snapping skipped for %s: %d keyframes, %v", absInput, len(keyframes), err)
+		} else {
+			pieces, segments, err = planPieces(ctx, absInput, segments, keyframes, opts.Snap)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// 映射需要保留的流, 精确模式的中间文件为 MPEG-TS, 按其支持情况规划
+	container := filepath.Ext(absOutput)
+	if hasEncodedPiece(pieces) {
+		container = ".ts"
+	}
 
-	// 捕获输出以便调试
-	out, err := cmd.CombinedOutput()
+	streamArgs, warnings, err := planOutputStreams(ctx, absInput, opts.Streams, container)
 	if err != nil {
-		return fmt.Errorf("ffmpeg failed: %w: %s", err, string(out))
+		return err
 	}
 
-	return nil
-}
+	// 保留拍摄时间、旋转角度等元数据, 原视频没有拍摄时间时使用浏览器提供的文件修改时间
+	md, err := probeMetadata(ctx, absInput)
+	if err != nil {
+		log.Printf("metadata probe skipped for %s: %v", absInput, err)
+	}
 
-// validateHeadTail 校验并规范化 head/tail 参数
-func validateHeadTail(headSec *int, tailSec int) error {
-	if *headSec < 0 {
-		return fmt.Errorf("invalid head seconds")
+	captured := md.captureTime(opts.ModTime)
+	metaArgs := metadataArgs(filepath.Ext(absOutput), md, captured)
+
+	// 隐私清理在同一次流复制中完成, 无法确认有哪些标签时不导出
+	var scrubbed []string
+
+	if opts.Scrub {
+		var scrubArgs []string
+
+		scrubArgs, scrubbed, err = planPrivacyScrub(ctx, absInput)
+		if err != nil {
+			return err
+		}
+
+		metaArgs = append(metaArgs, scrubArgs...)
 	}
 
-	if tailSec < 0 {
-		return fmt.Errorf("invalid tail seconds")
+	if onPlan != nil {
+		onPlan(cutPlan{Requested: requested, Segments: segments, Warnings: warnings, Scrubbed: scrubbed, CapturedAt: captured})
 	}
 
-	const maxHead = 24 * 3600 // 不允许超过一天
-	if *headSec > maxHead {
-		*headSec = maxHead
+	// 多个片段(或精确模式下的重新编码部分)时分段导出再拼接
+	if len(pieces) > 1 || (len(pieces) == 1 && len(pieces[0].Encode) > 0) {
+		return runSegments(ctx, ffmpegPath, absInput, absOutput, pieces, streamArgs, md, metaArgs, onProgress)
 	}
 
-	return nil
-}
+	// 预计输出时长, 用于计算进度百分比
+	seg := segments[0]
 
-// resolveAndValidatePaths 返回输入/输出的绝对路径并校验它们位于受控目录下
-func resolveAndValidatePaths(inputPath, outputPath string) (string, string, error) {
-	absInput, err := filepath.Abs(inputPath)
-	if err != nil {
-		return "", "", fmt.Errorf("invalid input path: %w", err)
+	total := seg.Len()
+	if seg.End == 0 && duration > 0 {
+		total = duration - seg.Start
 	}
 
-	absOutput, err := filepath.Abs(outputPath)
+	outArgs := append(append(streamArgs, "-map_metadata", "0"), metaArgs...)
+
+	return runFFmpegArgs(ctx, ffmpegPath, buildFFmpegArgs(absInput, absOutput, seg, outArgs), total, onProgress)
+}
+
+// runFFmpegArgs 以 -progress pipe:1 方式执行 ffmpeg 并解析进度, ctx 取消或超时时结束整个进程组
+// total 为预计输出时长, 未知时为 0
+func runFFmpegArgs(ctx context.Context, ffmpegPath string, args []string, total time.Duration, onProgress progressFunc) error {
+	// 通过 -progress pipe:1 输出机器可读的进度信息
+	args = append([]string{"-nostdin", "-nostats", "-progress", "pipe:1"}, args...)
+
+	cmd := newCommand(ctx, ffmpegPath, args...)
+
+	return runFFmpegCommand(cmd, total.Seconds(), onProgress)
+}
+
+// probeDurationForTrim 获取媒体时长; required 为 true 时时长必不可少, 否则获取失败返回 0 即可
+func probeDurationForTrim(ctx context.Context, absInput string, required bool) (time.Duration, error) {
+	ffprobePath, err := exec.LookPath("ffprobe")
 	if err != nil {
-		return "", "", fmt.Errorf("invalid output path: %w", err)
+		if required {
+			return 0, fmt.Errorf("ffprobe not found in PATH: %w", err)
+		}
+
+		return 0, nil
 	}
 
-	absUploadDir, err := filepath.Abs(uploadDir)
+	duration, err := getMediaDuration(ctx, ffprobePath, absInput)
 	if err != nil {
-		return "", "", fmt.Errorf("invalid upload dir: %w", err)
+		if required {
+			return 0, fmt.Errorf("failed to get media duration: %w", err)
+		}
+
+		log.Printf("get media duration error: %v", err)
+
+		return 0, nil
 	}
 
-	absOutputDir, err := filepath.Abs(outputDir)
-	if err != nil {
-		return "", "", fmt.Errorf("invalid output dir: %w", err)
+	return duration, nil
+}
+
+// newCommand 创建与 ctx 绑定的外部命令, ctx 结束时结束其进程组
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+
+	// 进程被结束后最多等待管道关闭的时间, 防止 Wait 一直阻塞
+	cmd.WaitDelay = 5 * time.Second
+
+	return cmd
+}
+
+// validateHeadTail 校验并规范化 head/tail 参数
+func validateHeadTail(head *time.Duration, tail time.Duration) error {
+	if *head < 0 {
+		return fmt.Errorf("invalid head duration")
 	}
 
-	if !(absInput == absUploadDir || strings.HasPrefix(absInput, absUploadDir+string(os.PathSeparator))) {
-		return "", "", fmt.Errorf("input path not allowed")
+	if tail < 0 {
+		return fmt.Errorf("invalid tail duration")
 	}
 
-	if !(absOutput == absOutputDir || strings.HasPrefix(absOutput, absOutputDir+string(os.PathSeparator))) {
-		return "", "", fmt.Errorf("output path not allowed")
+	const maxHead = 24 * time.Hour // 不允许超过一天
+	if *head > maxHead {
+		*head = maxHead
 	}
 
-	return absInput, absOutput, nil
+	return nil
 }
 
-// buildFFmpegArgs 根据是否需要去尾构建 ffmpeg 参数, 必要时会调用 ffprobe 获取时长
-func buildFFmpegArgs(absInput, absOutput string, headSec int, tailSec int) ([]string, error) {
-	if tailSec <= 0 {
-		return []string{
-			"-ss", strconv.Itoa(headSec),
-			"-i", absInput,
-			"-c", "copy",
-			"-avoid_negative_ts", "make_zero",
-			absOutput,
-		}, nil
+// resolveAndValidatePaths 返回输入/输出的绝对路径并校验它们位于受控目录下
+func resolveAndValidatePaths(inputPath, outputPath string) (string, string, error) {
+	absInput, err := resolveWithinDir(inputPath, uploadDir)
+	if err != nil {
+		return "", "", fmt.Errorf("input path: %w", err)
 	}
 
-	// 需要去尾：先查找 ffprobe 并获取时长
-	ffprobePath, err := exec.LookPath("ffprobe")
+	absOutput, err := resolveWithinDir(outputPath, outputDir)
 	if err != nil {
-		return nil, fmt.Errorf("ffprobe not found in PATH: %w", err)
+		return "", "", fmt.Errorf("output path: %w", err)
 	}
 
-	duration, err := getMediaDuration(ffprobePath, absInput)
+	return absInput, absOutput, nil
+}
+
+// resolveWithinDir 返回 path 的绝对路径并校验它位于 dir 目录下
+func resolveWithinDir(path, dir string) (string, error) {
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get media duration: %w", err)
+		return "", fmt.Errorf("invalid path: %w", err)
 	}
 
-	if duration <= 0 {
-		return nil, fmt.Errorf("invalid media duration: %v", duration)
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid dir: %w", err)
 	}
 
-	// 计算结束时间并验证
-	end := duration - float64(tailSec)
-	if end <= float64(headSec) {
-		return nil, fmt.Errorf("head + tail exceeds media duration")
+	if !(absPath == absDir || strings.HasPrefix(absPath, absDir+string(os.PathSeparator))) {
+		return "", fmt.Errorf("path not allowed")
 	}
 
-	dur := end - float64(headSec)
-	durStr := strconv.FormatFloat(dur, 'f', 3, 64)
+	return absPath, nil
+}
 
+// buildFFmpegArgs 构建以流复制方式导出单个片段的 ffmpeg 参数, 开放片段(End 为 0)导出到文件末尾
+// streamArgs 为 planStreams 生成的流映射参数, 为空时使用 ffmpeg 默认的流选择
+func buildFFmpegArgs(absInput, absOutput string, seg timeRange, streamArgs []string) []string {
 	args := []string{
-		"-ss", strconv.Itoa(headSec),
+		"-ss", formatFFmpegTime(seg.Start),
 		"-i", absInput,
-		"-t", durStr,
-		"-c", "copy",
-		"-avoid_negative_ts", "make_zero",
-		absOutput,
 	}
 
-	return args, nil
+	if seg.End > 0 {
+		args = append(args, "-t", formatFFmpegTime(seg.Len()))
+	}
+
+	args = append(args, "-c", "copy")
+	args = append(args, streamArgs...)
+
+	return append(args,
+		"-avoid_negative_ts", "make_zero",
+		absOutput,
+	)
 }
 
-// getMediaDuration 使用 ffprobe 获取媒体文件时长(秒)
-func getMediaDuration(ffprobePath, input string) (float64, error) {
-	cmd := exec.Command(ffprobePath, "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", input)
+// getMediaDuration 使用 ffprobe 获取媒体文件时长
+func getMediaDuration(ctx context.Context, ffprobePath, input string) (time.Duration, error) {
+	cmd := newCommand(ctx, ffprobePath, "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", input)
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -458,7 +368,7 @@ func getMediaDuration(ffprobePath, input string) (float64, error) {
 		return 0, err
 	}
 
-	return f, nil
+	return time.Duration(f * float64(time.Second)), nil
 }
 
 // respondAlertAndRedirect 响应一个 alert 弹窗并重定向到首页
@@ -493,32 +403,18 @@ func humanReadableBytes(n int64) string {
 	return fmt.Sprintf("%.1f %s", value, u)
 }
 
-// FileItem 用于模板渲染的文件信息
-type FileItem struct {
-	Name string
-	Link string
-}
-
-// generateResponse 输出处理完成后的下载页面
-func generateResponse(w http.ResponseWriter, processed []string, r *http.Request) {
-	// 生成处理完成后的 HTML 页面, 列出可下载文件
+// generateResponse 输出任务结果页面, 页面会轮询任务状态并在文件完成后提供下载
+func generateResponse(w http.ResponseWriter, job *Job, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	// 选择语言并加载翻译
 	lang := detectLangFromRequest(r)
 	i18n := getLocale(lang)
 
-	// 构建文件列表
-	files := make([]FileItem, len(processed))
-	for idx, fn := range processed {
-		files[idx] = FileItem{
-			Name: fn,
-			Link: fmt.Sprintf("/download/%s", fn),
-		}
-	}
+	// 使用 json.Marshal 安全序列化任务快照, 供页面脚本初始化
+	snap := job.Snapshot()
 
-	// 使用 json.Marshal 安全序列化文件名列表
-	filesJSONBytes, err := json.Marshal(processed)
+	jobJSONBytes, err := json.Marshal(snap)
 	if err != nil {
 		log.Printf("json marshal error: %v", err)
 		http.Error(w, "json marshal error", http.StatusInternalServerError)
@@ -534,8 +430,9 @@ func generateResponse(w http.ResponseWriter, processed []string, r *http.Request
 		DownloadText string
 		ReturnUpload string
 		NoFilesHint  string
-		Files        []FileItem
-		FilesJSON    string
+		I18n         map[string]string
+		Files        []*JobFile
+		JobJSON      string
 	}{
 		Lang:         lang,
 		Title:        i18n[KeyProcessedTitle],
@@ -543,8 +440,9 @@ func generateResponse(w http.ResponseWriter, processed []string, r *http.Request
 		DownloadText: i18n[KeyDownload],
 		ReturnUpload: i18n[KeyReturnUpload],
 		NoFilesHint:  i18n[KeyNoProcessedFilesHint],
-		Files:        files,
-		FilesJSON:    string(filesJSONBytes),
+		I18n:         i18n,
+		Files:        snap.Files,
+		JobJSON:      string(jobJSONBytes),
 	}
 
 	// 解析并执行模板
@@ -564,3 +462,45 @@ func generateResponse(w http.ResponseWriter, processed []string, r *http.Request
 		return
 	}
 }
+
+// wantsJSON 判断客户端是否期望 JSON 响应(脚本或 API 调用)
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// renderTemplate 解析 template.html 并以指定状态码渲染其中名为 name 的模板
+func renderTemplate(w http.ResponseWriter, status int, name string, data any) {
+	tmpl, err := template.New("template.html").Funcs(TemplateFuncMap).ParseFiles("template.html")
+	if err != nil {
+		log.Printf("parse template error: %v", err)
+		http.Error(w, "parse template error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("template execute error: %v", err)
+	}
+}
+
+// writeJSON 以指定状态码输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// deadlineAfter 返回从现在起 seconds 秒后的截止时间; seconds <= 0 表示不限时, 返回零值以清除截止时间(与 net/http 一致)
+func deadlineAfter(seconds int) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
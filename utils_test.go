@@ -0,0 +1,29 @@
+//
+// FilePath    : video-trim\utils_test.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 通用工具函数的测试
+//
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadlineAfter(t *testing.T) {
+	for _, seconds := range []int{0, -1} {
+		if got := deadlineAfter(seconds); !got.IsZero() {
+			t.Errorf("deadlineAfter(%d) = %v, want zero time", seconds, got)
+		}
+	}
+
+	before := time.Now()
+	got := deadlineAfter(15)
+
+	if got.Before(before.Add(15*time.Second)) || got.After(time.Now().Add(15*time.Second)) {
+		t.Errorf("deadlineAfter(15) = %v, want about 15s from %v", got, before)
+	}
+}
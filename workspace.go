@@ -0,0 +1,69 @@
+//
+// FilePath    : video-trim\workspace.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 任务工作目录: 每个任务的输入和处理中的输出放在独立目录中, 完成后再移入输出目录
+//
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 工作目录名前缀; 输出工作目录以点开头, 文件库、下载和自动清理都不会把它当作输出文件
+const (
+	jobUploadPrefix = "job_"
+	jobWorkPrefix   = ".job_"
+)
+
+// outputMu 串行化输出目录中的发布、重命名和删除, 保证不会覆盖已有文件
+var outputMu sync.Mutex
+
+// jobUploadDir 返回任务的上传工作目录, 保存该任务的上传和 tus 输入文件
+func jobUploadDir(id string) string {
+	return filepath.Join(uploadDir, jobUploadPrefix+id)
+}
+
+// jobWorkDir 返回任务的输出工作目录, ffmpeg 的输出、分段和封面临时文件都写在这里
+func jobWorkDir(id string) string {
+	return filepath.Join(outputDir, jobWorkPrefix+id)
+}
+
+// removeJobWorkspace 删除任务的上传和输出工作目录(包括其中残留的全部文件)
+func removeJobWorkspace(id string) {
+	os.RemoveAll(jobUploadDir(id))
+	os.RemoveAll(jobWorkDir(id))
+}
+
+// publishOutput 将工作目录中已完成的输出移入输出目录并登记归属的会话, 返回最终文件名
+// 文件名已存在时追加序号(如 xxx-cut-2.mp4), 在 outputMu 保护下检查并重命名, 不会覆盖已有文件
+func publishOutput(tmpPath, owner string) (string, error) {
+	ext := filepath.Ext(tmpPath)
+	base := strings.TrimSuffix(filepath.Base(tmpPath), ext)
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	name := base + ext
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(filepath.Join(outputDir, name)); os.IsNotExist(err) {
+			break
+		}
+
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(outputDir, name)); err != nil {
+		return "", err
+	}
+
+	outputOwners.Set(name, owner)
+
+	return name, nil
+}
@@ -0,0 +1,119 @@
+//
+// FilePath    : video-trim\zip.go
+// Author      : jiaopengzi
+// Blog        : https://jiaopengzi.com
+// Copyright   : Copyright (c) 2026 by jiaopengzi, All Rights Reserved.
+// Description : 打包下载: 将任务的全部输出文件以不压缩的 ZIP 流式发送, 不在内存或磁盘中生成完整压缩包
+//
+
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// handleJobZip 以 ZIP 流式下载任务中已完成的输出文件
+// 视频已经过压缩, 使用 Store 模式直接写入; 超过 4GB 时 archive/zip 自动写入 ZIP64 记录,
+// 非 ASCII 文件名(如中文)自动标记为 UTF-8 编码
+func handleJobZip(w http.ResponseWriter, r *http.Request) {
+	job, ok := getJobOrRespond(w, r)
+	if !ok {
+		return
+	}
+
+	snap := job.Snapshot()
+
+	var names []string
+	for _, f := range snap.Files {
+		if f.State == JobDone && f.Output != "" {
+			names = append(names, filepath.Base(f.Output))
+		}
+	}
+
+	if len(names) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "video-trim-" + snap.ID + ".zip"}))
+	w.Header().Set("Cache-Control", "no-store")
+
+	// 压缩包大小不固定, 按写入进度顺延写超时, 只在连接停滞时超时
+	zw := zip.NewWriter(&deadlineWriter{w: w, rc: http.NewResponseController(w)})
+
+	for _, name := range names {
+		if err := writeZipEntry(zw, filepath.Join(outputDir, name), name); err != nil {
+			// 响应头已发送, 只能中断连接, 客户端会得到不完整的压缩包
+			log.Printf("zip job %s: write %s error: %v", snap.ID, name, err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		log.Printf("zip job %s: close error: %v", snap.ID, err)
+		return
+	}
+
+	// 压缩包完整发送后删除已下载的文件
+	if deleteAfterDownload {
+		for _, name := range names {
+			expireOutput(name, "downloaded")
+		}
+	}
+}
+
+// writeZipEntry 将 path 以 Store 模式写入压缩包, 修改时间沿用文件的修改时间(即拍摄时间)
+// 文件已被删除时跳过
+func writeZipEntry(zw *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		log.Printf("zip: skip missing file %s", path)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	hdr := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: info.ModTime(),
+	}
+
+	ew, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(ew, f)
+
+	return err
+}
+
+// deadlineWriter 每次写入时顺延连接的写超时
+// 服务器的 write_timeout_seconds 限制整个响应, 大文件在慢速网络上会超时; 顺延后只在连接停滞时超时
+type deadlineWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+// Write 实现 io.Writer
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.rc.SetWriteDeadline(deadlineAfter(writeTimeoutSeconds))
+
+	return d.w.Write(p)
+}